@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// maxRollDays is a sanity limit on how far a date can be moved to get off holidays,
+// and how far into the term class sessions are counted
+const maxRollDays = 366
+
+func readCalendar(filename string, format canvas.Format) (*canvas.Calendar, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+	cal := new(canvas.Calendar)
+	if err = canvas.Decode(contents, cal, format); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", filename, err)
+	}
+	if err = checkCalendar(cal); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return cal, nil
+}
+
+func checkCalendar(cal *canvas.Calendar) error {
+	switch cal.Policy {
+	case "", canvas.PolicyForward, canvas.PolicyBackward, canvas.PolicyWarn:
+	default:
+		return fmt.Errorf("unknown calendar policy %q: expected %s, %s, or %s",
+			cal.Policy, canvas.PolicyForward, canvas.PolicyBackward, canvas.PolicyWarn)
+	}
+	for _, day := range cal.Holidays {
+		if day.Date == nil && (day.From == nil || day.To == nil) {
+			return fmt.Errorf("holiday %q needs a date or both from and to", day.Name)
+		}
+	}
+	for _, name := range cal.Meets {
+		if _, err := canvas.ParseWeekday(name); err != nil {
+			return fmt.Errorf("calendar meets: %v", err)
+		}
+	}
+	if cal.ClassTime != nil && !cal.ClassTime.IsTimeOfDay() {
+		return fmt.Errorf("calendar class_time should be a time of day like 10:30, not a date")
+	}
+	return nil
+}
+
+// mergeCalendars combines the holidays from several calendars.
+// For the other settings, the last one that is set wins.
+func mergeCalendars(cals ...*canvas.Calendar) *canvas.Calendar {
+	out := new(canvas.Calendar)
+	for _, cal := range cals {
+		if cal == nil {
+			continue
+		}
+		if cal.Policy != "" {
+			out.Policy = cal.Policy
+		}
+		if cal.TermStart != nil {
+			out.TermStart = cal.TermStart
+		}
+		if len(cal.Meets) > 0 {
+			out.Meets = cal.Meets
+		}
+		if cal.ClassTime != nil {
+			out.ClassTime = cal.ClassTime
+		}
+		out.Holidays = append(out.Holidays, cal.Holidays...)
+	}
+	return out
+}
+
+// holidayOn returns the holiday that covers the local date of t, or nil
+func holidayOn(cal *canvas.Calendar, t time.Time) *canvas.Holiday {
+	day := dateOf(t)
+	for _, holiday := range cal.Holidays {
+		if holiday.Date != nil && day.Equal(dateOf(holiday.Date.Time)) {
+			return holiday
+		}
+		if holiday.From != nil && holiday.To != nil &&
+			!day.Before(dateOf(holiday.From.Time)) && !day.After(dateOf(holiday.To.Time)) {
+			return holiday
+		}
+	}
+	return nil
+}
+
+// dateOf strips the time of day from t
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Local().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// rollDate moves a timestamp off holidays a day at a time according to the
+// policy, keeping its time of day. It also returns the holiday the date
+// landed on, or nil if it did not land on one.
+func rollDate(cal *canvas.Calendar, policy string, t time.Time) (time.Time, *canvas.Holiday, error) {
+	local := t.Local()
+	if year, month, day := local.Date(); year == 0 && month == time.January && day == 1 {
+		// a bare time of day
+		return t, nil, nil
+	}
+	first := holidayOn(cal, local)
+	if first == nil || policy == canvas.PolicyWarn {
+		return t, first, nil
+	}
+
+	step := 1
+	if policy == canvas.PolicyBackward {
+		step = -1
+	}
+	for i := 0; i < maxRollDays; i++ {
+		local = local.AddDate(0, 0, step)
+		if holidayOn(cal, local) == nil {
+			return local, first, nil
+		}
+	}
+	return t, nil, fmt.Errorf("unable to find a day that is not a holiday near %s", t.Local().Format("2006-01-02"))
+}
+
+// rollField rolls one date of an assignment or quiz (named by owner) off of holidays
+// and logs a warning if it was moved (or would have been, with the warn policy). Dates
+// may be shared with a default, so a moved date is replaced rather than changed in place.
+func rollField(cal *canvas.Calendar, policy string, owner string, name string, field **canvas.Time) error {
+	if cal == nil || len(cal.Holidays) == 0 || *field == nil {
+		return nil
+	}
+	before := (*field).Time
+	after, holiday, err := rollDate(cal, policy, before)
+	if err != nil {
+		return fmt.Errorf("%s of %q: %v", name, owner, err)
+	}
+	if holiday == nil {
+		return nil
+	}
+	if policy == canvas.PolicyWarn {
+		log.Printf("warning: %s of %q is %s, which falls on %s",
+			name, owner, before.Local().Format("2006-01-02 15:04"), holiday.Name)
+		return nil
+	}
+	log.Printf("warning: moved %s of %q from %s to %s to avoid %s",
+		name, owner, before.Local().Format("2006-01-02 15:04"), after.Format("2006-01-02 15:04"), holiday.Name)
+	*field = &canvas.Time{Time: after}
+	return nil
+}
+
+// calendarPolicy returns the policy for a calendar, which defaults to forward
+func calendarPolicy(cal *canvas.Calendar) string {
+	if cal == nil || cal.Policy == "" {
+		return canvas.PolicyForward
+	}
+	return cal.Policy
+}
+
+// applyCalendar rolls the dates in an assignment off of holidays, except for
+// due_at, which applyDefaults rolls before computing other dates from it.
+// Unlock dates always move earlier so the time students have to work never shrinks.
+func applyCalendar(cal *canvas.Calendar, asst *canvas.Assignment) error {
+	policy := calendarPolicy(cal)
+	unlockPolicy := canvas.PolicyBackward
+	if policy == canvas.PolicyWarn {
+		unlockPolicy = canvas.PolicyWarn
+	}
+
+	if err := rollField(cal, policy, asst.Name, "lock_at", &asst.LockAt); err != nil {
+		return err
+	}
+	if err := rollField(cal, unlockPolicy, asst.Name, "unlock_at", &asst.UnlockAt); err != nil {
+		return err
+	}
+	if err := rollField(cal, policy, asst.Name, "peer_reviews_assign_at", &asst.PeerReviewsAssignAt); err != nil {
+		return err
+	}
+	if topic := asst.DiscussionTopic; topic != nil {
+		if err := rollField(cal, unlockPolicy, asst.Name, "discussion_topic.delayed_post_at", &topic.DelayedPostAt); err != nil {
+			return err
+		}
+	}
+
+	for i, override := range asst.Overrides {
+		prefix := fmt.Sprintf("overrides[%d].", i)
+		if err := rollField(cal, policy, asst.Name, prefix+"due_at", &override.DueAt); err != nil {
+			return err
+		}
+		if err := rollField(cal, policy, asst.Name, prefix+"lock_at", &override.LockAt); err != nil {
+			return err
+		}
+		if err := rollField(cal, unlockPolicy, asst.Name, prefix+"unlock_at", &override.UnlockAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyQuizCalendar rolls every date in a quiz off of holidays, using the same
+// policies as applyCalendar does for an assignment.
+func applyQuizCalendar(cal *canvas.Calendar, quiz *canvas.Quiz) error {
+	policy := calendarPolicy(cal)
+	unlockPolicy := canvas.PolicyBackward
+	if policy == canvas.PolicyWarn {
+		unlockPolicy = canvas.PolicyWarn
+	}
+
+	if err := rollField(cal, policy, quiz.Title, "due_at", &quiz.DueAt); err != nil {
+		return err
+	}
+	if err := rollField(cal, policy, quiz.Title, "lock_at", &quiz.LockAt); err != nil {
+		return err
+	}
+	return rollField(cal, unlockPolicy, quiz.Title, "unlock_at", &quiz.UnlockAt)
+}
+
+// applyDiscussionCalendar rolls the dates in an ungraded discussion off of
+// holidays. The delayed post date is treated like an unlock date.
+func applyDiscussionCalendar(cal *canvas.Calendar, topic *canvas.DiscussionTopic) error {
+	policy := calendarPolicy(cal)
+	unlockPolicy := canvas.PolicyBackward
+	if policy == canvas.PolicyWarn {
+		unlockPolicy = canvas.PolicyWarn
+	}
+
+	if err := rollField(cal, unlockPolicy, topic.Title, "delayed_post_at", &topic.DelayedPostAt); err != nil {
+		return err
+	}
+	return rollField(cal, policy, topic.Title, "lock_at", &topic.LockAt)
+}
+
+// resolveTimes replaces the class-relative dates in an entry with timestamps.
+// kind and name identify the entry in error messages.
+func resolveTimes(cal *canvas.Calendar, kind, name string, times []*canvas.Time) error {
+	for _, t := range times {
+		if t.Relative == nil {
+			continue
+		}
+		resolved, err := resolveDate(cal, t.Relative)
+		if err != nil {
+			return fmt.Errorf("%s %q: %v", kind, name, err)
+		}
+		*t = canvas.Time{Time: resolved}
+	}
+	return nil
+}
+
+// resolveDate finds the timestamp for a class-relative date. If the date
+// does not give a time of day, the class time is used; if there is no class
+// time, the result is a bare date so a default's time of day can apply.
+func resolveDate(cal *canvas.Calendar, rel *canvas.RelativeDate) (time.Time, error) {
+	if cal == nil || cal.TermStart == nil {
+		return time.Time{}, fmt.Errorf("%q needs a calendar entry with a term_start", rel.Text)
+	}
+	start := cal.TermStart.Local()
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+
+	var day time.Time
+	if rel.Week > 0 {
+		// weeks line up the same way they do for -shift_to
+		offset := (int(rel.Weekday) - int(termWeekStart) + 7) % 7
+		day = weekStart(start).AddDate(0, 0, (rel.Week-1)*7+offset)
+	} else {
+		meets := make(map[time.Weekday]bool)
+		for _, name := range cal.Meets {
+			weekday, err := canvas.ParseWeekday(name)
+			if err != nil {
+				return time.Time{}, err
+			}
+			meets[weekday] = true
+		}
+		if len(meets) == 0 {
+			return time.Time{}, fmt.Errorf("%q needs a calendar entry with meeting days", rel.Text)
+		}
+
+		// count class meetings, skipping holidays
+		count := 0
+		for i := 0; i < maxRollDays && count < rel.Session; i++ {
+			day = start.AddDate(0, 0, i)
+			if meets[day.Weekday()] && holidayOn(cal, day) == nil {
+				count++
+			}
+		}
+		if count < rel.Session {
+			return time.Time{}, fmt.Errorf("%q is more than a year after the term starts", rel.Text)
+		}
+	}
+
+	// add the time of day
+	switch {
+	case rel.HasTimeOfDay:
+		day = day.Add(rel.TimeOfDay)
+	case cal.ClassTime != nil:
+		class := cal.ClassTime.Local()
+		day = time.Date(day.Year(), day.Month(), day.Day(), class.Hour(), class.Minute(), class.Second(), 0, time.Local)
+	}
+
+	return day.Add(rel.Offset), nil
+}
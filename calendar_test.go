@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// day returns midnight local time on a date in 2024
+func day(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.Local)
+}
+
+func at(t time.Time, hour, minute int) time.Time {
+	return t.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func localTime(t time.Time) *canvas.Time {
+	return &canvas.Time{Time: t}
+}
+
+func TestResolveDate(t *testing.T) {
+	// the term starts on a Wednesday; week 1 runs from Sunday, January 7
+	cal := &canvas.Calendar{
+		TermStart: localTime(at(day(time.January, 10), 8, 0)),
+		Meets:     []string{"mon", "wed", "fri"},
+		Holidays: []*canvas.Holiday{
+			{Name: "MLK Day", Date: localTime(day(time.January, 15))},
+			{Name: "Break", From: localTime(day(time.January, 24)), To: localTime(day(time.January, 26))},
+		},
+	}
+	withClassTime := *cal
+	withClassTime.ClassTime = &canvas.Time{Time: time.Date(0, time.January, 1, 10, 30, 0, 0, time.Local)}
+
+	tests := []struct {
+		cal  *canvas.Calendar
+		in   string
+		want time.Time
+	}{
+		// weeks start on Sunday, even before the term starts
+		{cal, "week 1 sun", day(time.January, 7)},
+		{cal, "week 1 mon", day(time.January, 8)},
+		{cal, "week 1 wed", day(time.January, 10)},
+		{cal, "week 1 sat", day(time.January, 13)},
+		{cal, "week 2 sun", day(time.January, 14)},
+		{cal, "week 5 thu", day(time.February, 8)},
+
+		// holidays do not change week-relative dates
+		{cal, "week 2 mon", day(time.January, 15)},
+
+		// sessions count meetings from the term start, skipping holidays
+		{cal, "session 1", day(time.January, 10)},
+		{cal, "session 2", day(time.January, 12)},
+		{cal, "session 3", day(time.January, 17)},
+		{cal, "session 4", day(time.January, 19)},
+		{cal, "session 5", day(time.January, 22)},
+		{cal, "session 6", day(time.January, 29)},
+
+		// times of day and offsets
+		{cal, "week 2 tue 23:59", at(day(time.January, 16), 23, 59)},
+		{&withClassTime, "week 2 tue", at(day(time.January, 16), 10, 30)},
+		{&withClassTime, "week 2 tue 9:00", at(day(time.January, 16), 9, 0)},
+		{&withClassTime, "session 3 + 2h", at(day(time.January, 17), 12, 30)},
+		{&withClassTime, "session 2 - 30m", at(day(time.January, 12), 10, 0)},
+		{&withClassTime, "session 1 - 11h", at(day(time.January, 9), 23, 30)},
+	}
+	for _, test := range tests {
+		rel, err := canvas.ParseRelative(test.in)
+		if err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		got, err := resolveDate(test.cal, rel)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+		} else if !got.Equal(test.want) {
+			t.Errorf("%q: got %s, expected %s", test.in, got.Format(time.RFC1123), test.want.Format(time.RFC1123))
+		}
+	}
+}
+
+func TestResolveDateErrors(t *testing.T) {
+	start := localTime(day(time.January, 10))
+	tests := []struct {
+		cal  *canvas.Calendar
+		in   string
+		want string
+	}{
+		{nil, "week 1 mon", "needs a calendar entry with a term_start"},
+		{&canvas.Calendar{Meets: []string{"mon"}}, "session 1", "needs a calendar entry with a term_start"},
+		{&canvas.Calendar{TermStart: start}, "session 1", "needs a calendar entry with meeting days"},
+		{&canvas.Calendar{TermStart: start, Meets: []string{"mon"}}, "session 60", "more than a year after the term starts"},
+	}
+	for _, test := range tests {
+		rel, err := canvas.ParseRelative(test.in)
+		if err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		_, err = resolveDate(test.cal, rel)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.in)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %q, expected it to mention %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestRollDate(t *testing.T) {
+	thanksgiving := &canvas.Holiday{Name: "Thanksgiving", From: localTime(day(time.November, 27)), To: localTime(day(time.November, 29))}
+	closure := &canvas.Holiday{Name: "Closure", Date: localTime(day(time.November, 30))}
+	labor := &canvas.Holiday{Name: "Labor Day", Date: localTime(day(time.September, 2))}
+	cal := &canvas.Calendar{Holidays: []*canvas.Holiday{labor, thanksgiving, closure}}
+
+	tests := []struct {
+		policy  string
+		in      time.Time
+		want    time.Time
+		holiday *canvas.Holiday
+	}{
+		// days that are not holidays stay put
+		{canvas.PolicyForward, at(day(time.September, 3), 23, 59), at(day(time.September, 3), 23, 59), nil},
+		{canvas.PolicyBackward, at(day(time.November, 26), 9, 0), at(day(time.November, 26), 9, 0), nil},
+
+		// single days keep their time of day
+		{canvas.PolicyForward, at(day(time.September, 2), 23, 59), at(day(time.September, 3), 23, 59), labor},
+		{canvas.PolicyBackward, at(day(time.September, 2), 23, 59), at(day(time.September, 1), 23, 59), labor},
+		{canvas.PolicyForward, day(time.September, 2), day(time.September, 3), labor},
+
+		// ranges, including a holiday right after the range
+		{canvas.PolicyForward, at(day(time.November, 27), 17, 0), at(day(time.December, 1), 17, 0), thanksgiving},
+		{canvas.PolicyForward, at(day(time.November, 29), 17, 0), at(day(time.December, 1), 17, 0), thanksgiving},
+		{canvas.PolicyBackward, at(day(time.November, 29), 17, 0), at(day(time.November, 26), 17, 0), thanksgiving},
+		{canvas.PolicyBackward, at(day(time.November, 30), 17, 0), at(day(time.November, 26), 17, 0), closure},
+
+		// warn reports the holiday without moving the date
+		{canvas.PolicyWarn, at(day(time.November, 28), 12, 0), at(day(time.November, 28), 12, 0), thanksgiving},
+	}
+	for _, test := range tests {
+		got, holiday, err := rollDate(cal, test.policy, test.in)
+		if err != nil {
+			t.Errorf("%s %s: %v", test.policy, test.in.Format(time.RFC1123), err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s %s: got %s, expected %s", test.policy, test.in.Format(time.RFC1123), got.Format(time.RFC1123), test.want.Format(time.RFC1123))
+		}
+		if holiday != test.holiday {
+			t.Errorf("%s %s: got holiday %v, expected %v", test.policy, test.in.Format(time.RFC1123), holiday, test.holiday)
+		}
+	}
+}
+
+func TestRollDateTimeOfDay(t *testing.T) {
+	// a bare time of day has no date to move, even if January 1 is a holiday
+	cal := &canvas.Calendar{Holidays: []*canvas.Holiday{{Name: "New Year", Date: localTime(time.Date(0, time.January, 1, 0, 0, 0, 0, time.Local))}}}
+	in := time.Date(0, time.January, 1, 10, 30, 0, 0, time.Local)
+	got, holiday, err := rollDate(cal, canvas.PolicyForward, in)
+	if err != nil || !got.Equal(in) || holiday != nil {
+		t.Errorf("got %s, %v, %v; expected the time of day unchanged", got, holiday, err)
+	}
+}
+
+func TestRollDateNoEscape(t *testing.T) {
+	cal := &canvas.Calendar{Holidays: []*canvas.Holiday{
+		{Name: "Sabbatical", From: localTime(day(time.January, 1)), To: localTime(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local))},
+	}}
+	_, _, err := rollDate(cal, canvas.PolicyForward, day(time.March, 1))
+	if err == nil || !strings.Contains(err.Error(), "unable to find a day that is not a holiday near 2024-03-01") {
+		t.Errorf("got %v, expected an error", err)
+	}
+}
+
+func TestApplyDefaultsRollsDueOnce(t *testing.T) {
+	cal := &canvas.Calendar{Holidays: []*canvas.Holiday{{Name: "Snow day", Date: localTime(day(time.January, 12))}}}
+	tests := []struct {
+		name     string
+		policy   string
+		template string
+		due      time.Time
+	}{
+		{"warn with a default", canvas.PolicyWarn, `[
+			{"assignment": {"default": true, "lock_after": "48h"}},
+			{"assignment": {"name": "Lab 1", "due_at": "2024-01-12 23:59:00"}}
+		]`, at(day(time.January, 12), 23, 59)},
+		{"warn without a default", canvas.PolicyWarn, `[
+			{"assignment": {"name": "Lab 1", "due_at": "2024-01-12 23:59:00"}}
+		]`, at(day(time.January, 12), 23, 59)},
+		{"forward with a default", canvas.PolicyForward, `[
+			{"assignment": {"default": true, "lock_after": "48h"}},
+			{"assignment": {"name": "Lab 1", "due_at": "2024-01-12 23:59:00"}}
+		]`, at(day(time.January, 13), 23, 59)},
+		{"forward without a default", canvas.PolicyForward, `[
+			{"assignment": {"name": "Lab 1", "due_at": "2024-01-12 23:59:00"}}
+		]`, at(day(time.January, 13), 23, 59)},
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	for _, test := range tests {
+		buf.Reset()
+		var entries []canvas.AssignmentOrGroup
+		if err := canvas.Decode([]byte(test.template), &entries, canvas.JSON); err != nil {
+			t.Fatal(err)
+		}
+		side := *cal
+		side.Policy = test.policy
+		out, _, err := applyDefaults(entries, ".", 1, &side, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		asst := out[len(out)-1].Assignment
+		if !asst.DueAt.Equal(test.due) {
+			t.Errorf("%s: due_at is %s, expected %s", test.name, asst.DueAt.Format(time.RFC1123), test.due.Format(time.RFC1123))
+		}
+		if asst.LockAt != nil && !asst.LockAt.Equal(test.due.Add(48*time.Hour)) {
+			t.Errorf("%s: lock_at %s was not computed from the rolled due date", test.name, asst.LockAt.Format(time.RFC1123))
+		}
+		if n := strings.Count(buf.String(), "due_at of"); n != 1 {
+			t.Errorf("%s: due_at was reported %d times:\n%s", test.name, n, buf.String())
+		}
+	}
+}
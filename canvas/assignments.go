@@ -0,0 +1,162 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// SubmissionTypes are the values that Canvas accepts in submission_types
+var SubmissionTypes = []string{
+	"none", "on_paper", "online_quiz", "discussion_topic", "external_tool", "online_upload",
+	"online_text_entry", "online_url", "media_recording", "student_annotation",
+}
+
+// GradingTypes are the values that Canvas accepts for grading_type
+var GradingTypes = []string{"pass_fail", "percent", "letter_grade", "gpa_scale", "points", "not_graded"}
+
+// OriginalityReportVisibilities are the values that Canvas accepts for the
+// originality_report_visibility of Turnitin settings
+var OriginalityReportVisibilities = []string{"immediate", "after_grading", "after_due_date", "never"}
+
+// includeOverrides asks Canvas to return overrides along with assignments
+var includeOverrides = url.Values{"include[]": {"overrides"}}
+
+// GetAssignment fetches a single assignment with its overrides.
+func (c *Client) GetAssignment(ctx context.Context, courseID, assignmentID int) (*Assignment, error) {
+	asst := new(Assignment)
+	path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d", courseID, assignmentID)
+	if err := c.Get(ctx, path, includeOverrides, asst); err != nil {
+		return nil, err
+	}
+	return asst, nil
+}
+
+// ListAssignments fetches every assignment in a course with its overrides.
+func (c *Client) ListAssignments(ctx context.Context, courseID int) ([]*Assignment, error) {
+	var assts []*Assignment
+	path := fmt.Sprintf("/api/v1/courses/%d/assignments", courseID)
+	if err := c.GetAll(ctx, path, includeOverrides, &assts); err != nil {
+		return nil, err
+	}
+	return assts, nil
+}
+
+// ListGroupAssignments fetches every assignment in an assignment group with its overrides.
+func (c *Client) ListGroupAssignments(ctx context.Context, courseID, assignmentGroupID int) ([]*Assignment, error) {
+	var assts []*Assignment
+	path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups/%d/assignments", courseID, assignmentGroupID)
+	if err := c.GetAll(ctx, path, includeOverrides, &assts); err != nil {
+		return nil, err
+	}
+	return assts, nil
+}
+
+// SaveAssignment creates the assignment if it has no ID, or updates it otherwise.
+// It returns the assignment as Canvas reports it after the change.
+// Overrides and rubrics are not saved; use SyncOverrides and SaveAssignmentRubric for those.
+// Neither are discussion settings; use SaveGradedDiscussion with the topic Canvas reports.
+func (c *Client) SaveAssignment(ctx context.Context, courseID int, elt *Assignment) (*Assignment, error) {
+	asst := *elt
+	asst.Explicit = asst.Explicit.Without("default", "extends", "overrides", "rubric", "rubric_settings", "rubric_ref", "discussion_topic", "description_file")
+	asst.Extends = ""
+	asst.Overrides = nil
+	asst.Rubric = nil
+	asst.RubricSettings = nil
+	asst.RubricRef = ""
+	asst.DiscussionTopic = nil
+	asst.DescriptionFile = ""
+	body := &AssignmentOrGroup{Assignment: &asst}
+	result := new(Assignment)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignments", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d", courseID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// DeleteAssignment deletes an assignment along with any submissions it has.
+func (c *Client) DeleteAssignment(ctx context.Context, courseID, assignmentID int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d", courseID, assignmentID)
+	return c.Delete(ctx, path, nil, nil)
+}
+
+// GetAssignmentGroup fetches a single assignment group (without its assignments).
+func (c *Client) GetAssignmentGroup(ctx context.Context, courseID, assignmentGroupID int) (*AssignmentGroup, error) {
+	group := new(AssignmentGroup)
+	path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups/%d", courseID, assignmentGroupID)
+	if err := c.Get(ctx, path, nil, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListAssignmentGroups fetches every assignment group in a course.
+// If includeAssignments is set, each group's assignments are fetched as well.
+func (c *Client) ListAssignmentGroups(ctx context.Context, courseID int, includeAssignments bool) ([]*AssignmentGroup, error) {
+	var groups []*AssignmentGroup
+	path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups", courseID)
+	if err := c.GetAll(ctx, path, nil, &groups); err != nil {
+		return nil, err
+	}
+	if !includeAssignments {
+		return groups, nil
+	}
+
+	// fetch all assignments in one listing and file them by group
+	assts, err := c.ListAssignments(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*AssignmentGroup)
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+	for _, asst := range assts {
+		group, present := byID[asst.AssignmentGroupID]
+		if !present {
+			return nil, fmt.Errorf("assignment %d (%s) is in unknown group %d", asst.ID, asst.Name, asst.AssignmentGroupID)
+		}
+		group.Assignments = append(group.Assignments, asst)
+	}
+	return groups, nil
+}
+
+// SaveAssignmentGroup creates the group if it has no ID, or updates it otherwise.
+// It returns the group as Canvas reports it after the change.
+func (c *Client) SaveAssignmentGroup(ctx context.Context, courseID int, elt *AssignmentGroup) (*AssignmentGroup, error) {
+	group := *elt
+	group.Default = nil
+	group.Explicit = group.Explicit.Without("default", "assignments")
+	result := new(AssignmentGroup)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups", courseID)
+		if err := c.Post(ctx, path, &group, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups/%d", courseID, elt.ID)
+		if err := c.Put(ctx, path, &group, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// DeleteAssignmentGroup deletes an assignment group. If moveAssignmentsTo is non-zero,
+// the group's assignments are moved to that group; otherwise they are deleted with it.
+func (c *Client) DeleteAssignmentGroup(ctx context.Context, courseID, assignmentGroupID, moveAssignmentsTo int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/assignment_groups/%d", courseID, assignmentGroupID)
+	params := make(url.Values)
+	if moveAssignmentsTo != 0 {
+		params.Set("move_assignments_to", fmt.Sprintf("%d", moveAssignmentsTo))
+	}
+	return c.Delete(ctx, path, params, nil)
+}
@@ -0,0 +1,161 @@
+package canvas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar describes the class schedule for a term and the days when nothing
+// should be due. It appears in template files as its own entry, e.g.:
+//
+//	{"calendar": {
+//	    "term_start": "2014-08-25",
+//	    "meets": ["mon", "wed", "fri"],
+//	    "class_time": "10:30",
+//	    "policy": "forward",
+//	    "holidays": [
+//	        {"name": "Labor Day", "date": "2014-09-01"},
+//	        {"name": "Thanksgiving", "from": "2014-11-26", "to": "2014-11-28"}
+//	    ]
+//	}}
+type Calendar struct {
+	// TermStart, Meets, and ClassTime are used to resolve class-relative
+	// dates like "week 5 thu" or "session 12"
+	TermStart *Time    `json:"term_start,omitempty" yaml:"term_start,omitempty"`
+	Meets     []string `json:"meets,omitempty" yaml:"meets,omitempty,flow"`
+	ClassTime *Time    `json:"class_time,omitempty" yaml:"class_time,omitempty"`
+
+	// Policy says how to move a date that lands on a holiday:
+	// forward (the default), backward, or warn to leave it alone.
+	Policy   string     `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Holidays []*Holiday `json:"holidays,omitempty" yaml:"holidays,omitempty"`
+}
+
+// Holiday is a single day (Date) or an inclusive range of days (From and To).
+type Holiday struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Date *Time  `json:"date,omitempty" yaml:"date,omitempty"`
+	From *Time  `json:"from,omitempty" yaml:"from,omitempty"`
+	To   *Time  `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// Calendar policies
+const (
+	PolicyForward  = "forward"
+	PolicyBackward = "backward"
+	PolicyWarn     = "warn"
+)
+
+// RelativeDate is a date given relative to the class schedule in a Calendar.
+type RelativeDate struct {
+	// Text is the date as it was written
+	Text string
+
+	// Week and Weekday are set for dates like "week 5 thu", where week 1
+	// is the week containing the first day of the term
+	Week    int
+	Weekday time.Weekday
+
+	// Session is set for dates like "session 12", counting class meetings from 1
+	Session int
+
+	// TimeOfDay is the time given explicitly, e.g., "week 5 thu 23:59";
+	// HasTimeOfDay is false if the class time should be used
+	TimeOfDay    time.Duration
+	HasTimeOfDay bool
+
+	// Offset is added at the end, e.g., "session 12 + 2h"
+	Offset time.Duration
+}
+
+// IsRelative reports whether s looks like a class-relative date.
+func IsRelative(s string) bool {
+	fields := strings.Fields(strings.ToLower(s))
+	return len(fields) > 0 && (fields[0] == "week" || fields[0] == "session")
+}
+
+// ParseRelative parses a class-relative date:
+//
+//	week N DAY [HH:MM[:SS]] [+|- DURATION]
+//	session N [HH:MM[:SS]] [+|- DURATION]
+//
+// for example "week 5 thu", "week 3 fri 23:59", or "session 12 + 2h".
+func ParseRelative(s string) (*RelativeDate, error) {
+	rel := &RelativeDate{Text: s}
+	fields := strings.Fields(strings.NewReplacer("+", " + ", "-", " - ", ",", " ").Replace(strings.ToLower(s)))
+	bad := func(msg string) (*RelativeDate, error) {
+		return nil, fmt.Errorf("invalid relative date %q: %s", s, msg)
+	}
+	if len(fields) < 2 {
+		return bad("expected week N DAY or session N")
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n < 1 {
+		return bad("expected a number starting at 1 after " + fields[0])
+	}
+
+	i := 2
+	switch fields[0] {
+	case "week":
+		if len(fields) < 3 {
+			return bad("expected a day of the week after the week number")
+		}
+		if rel.Weekday, err = ParseWeekday(fields[2]); err != nil {
+			return bad(err.Error())
+		}
+		rel.Week = n
+		i = 3
+	case "session":
+		rel.Session = n
+	default:
+		return bad("expected week N DAY or session N")
+	}
+
+	// optional time of day
+	if i < len(fields) && strings.Contains(fields[i], ":") {
+		var t time.Time
+		if t, err = time.Parse("15:04:05", fields[i]); err != nil {
+			if t, err = time.Parse("15:04", fields[i]); err != nil {
+				return bad("unknown time of day " + fields[i])
+			}
+		}
+		rel.TimeOfDay = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+		rel.HasTimeOfDay = true
+		i++
+	}
+
+	// optional offset
+	if i < len(fields) && (fields[i] == "+" || fields[i] == "-") {
+		if i+1 >= len(fields) {
+			return bad("expected a duration after " + fields[i])
+		}
+		if rel.Offset, err = time.ParseDuration(fields[i+1]); err != nil {
+			return bad(err.Error())
+		}
+		if fields[i] == "-" {
+			rel.Offset = -rel.Offset
+		}
+		i += 2
+	}
+
+	if i < len(fields) {
+		return bad("unexpected " + fields[i])
+	}
+	return rel, nil
+}
+
+// ParseWeekday parses a day name such as "thu" or "Thursday".
+func ParseWeekday(s string) (time.Weekday, error) {
+	lower := strings.ToLower(s)
+	if len(lower) >= 2 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			name := strings.ToLower(day.String())
+			if strings.HasPrefix(name, lower) {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown day of the week %q", s)
+}
@@ -0,0 +1,80 @@
+package canvas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRelative(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RelativeDate
+	}{
+		{"week 5 thu", RelativeDate{Week: 5, Weekday: time.Thursday}},
+		{"Week 1 Sunday", RelativeDate{Week: 1, Weekday: time.Sunday}},
+		{"week 3 fri 23:59", RelativeDate{Week: 3, Weekday: time.Friday, TimeOfDay: 23*time.Hour + 59*time.Minute, HasTimeOfDay: true}},
+		{"week 2 mo 9:00:30", RelativeDate{Week: 2, Weekday: time.Monday, TimeOfDay: 9*time.Hour + 30*time.Second, HasTimeOfDay: true}},
+		{"session 12", RelativeDate{Session: 12}},
+		{"session 12 + 2h", RelativeDate{Session: 12, Offset: 2 * time.Hour}},
+		{"session 1+90m", RelativeDate{Session: 1, Offset: 90 * time.Minute}},
+		{"session 2 10:30 - 30m", RelativeDate{Session: 2, TimeOfDay: 10*time.Hour + 30*time.Minute, HasTimeOfDay: true, Offset: -30 * time.Minute}},
+		{"week 1 monday, 9:00 +1h30m", RelativeDate{Week: 1, Weekday: time.Monday, TimeOfDay: 9 * time.Hour, HasTimeOfDay: true, Offset: 90 * time.Minute}},
+	}
+	for _, test := range tests {
+		got, err := ParseRelative(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		test.want.Text = test.in
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%q: got %+v, expected %+v", test.in, *got, test.want)
+		}
+	}
+}
+
+func TestParseRelativeErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"week", "expected week N DAY or session N"},
+		{"week 0 mon", "expected a number starting at 1 after week"},
+		{"session x", "expected a number starting at 1 after session"},
+		{"week 2", "expected a day of the week after the week number"},
+		{"week 2 m", "unknown day of the week"},
+		{"week 2 someday", "unknown day of the week"},
+		{"session 3 25:00", "unknown time of day 25:00"},
+		{"session 3 +", "expected a duration after +"},
+		{"session 3 + 2days", "unknown unit"},
+		{"session 3 extra", "unexpected extra"},
+		{"month 3", "expected week N DAY or session N"},
+	}
+	for _, test := range tests {
+		_, err := ParseRelative(test.in)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.in)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %q, expected it to mention %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestIsRelative(t *testing.T) {
+	tests := map[string]bool{
+		"week 5 thu":       true,
+		"  Session 3":      true,
+		"2024-01-01":       false,
+		"10:30":            false,
+		"weekly":           false,
+		"":                 false,
+		"sessions 2 and 3": false,
+	}
+	for in, want := range tests {
+		if got := IsRelative(in); got != want {
+			t.Errorf("IsRelative(%q) = %v, expected %v", in, got, want)
+		}
+	}
+}
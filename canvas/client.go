@@ -0,0 +1,309 @@
+// Package canvas is a client for the parts of the Canvas LMS REST API
+// that deal with assignments and assignment groups.
+package canvas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxPages is a sanity limit on how many pages a single listing may span
+const MaxPages = 1000
+
+// Client talks to a single Canvas instance on behalf of a single user.
+type Client struct {
+	// BaseURL is the root of the Canvas instance, e.g., https://dixie.instructure.com
+	BaseURL string
+
+	// Token is the API access token sent as a bearer token
+	Token string
+
+	// HTTPClient is used for all requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// PerPage is the page size requested for listings. If zero, Canvas picks.
+	PerPage int
+
+	// MaxRetries is how many times a request is retried after throttling, or
+	// for an idempotent request (GET, PUT, DELETE), after a network error or a
+	// 5xx response.
+	MaxRetries int
+
+	// RetryWait is the base delay before the first retry. It doubles with each
+	// attempt (with random jitter) up to MaxRetryWait.
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+
+	// RateLimitFloor is the X-Rate-Limit-Remaining level below which the client
+	// pauses before each request to let the Canvas quota recover.
+	RateLimitFloor float64
+
+	// Logf, if set, is used to report retries and throttling pauses.
+	Logf func(format string, args ...interface{})
+
+	mu            sync.Mutex
+	rateKnown     bool
+	rateRemaining float64
+	requestCost   float64
+}
+
+// NewClient returns a client for the Canvas instance at baseURL.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:        strings.TrimSuffix(baseURL, "/"),
+		Token:          token,
+		HTTPClient:     http.DefaultClient,
+		PerPage:        100,
+		MaxRetries:     5,
+		RetryWait:      time.Second,
+		MaxRetryWait:   time.Minute,
+		RateLimitFloor: 100,
+	}
+}
+
+// URL returns the full URL for an API path such as /api/v1/courses/1/assignments.
+func (c *Client) URL(path string, params url.Values) string {
+	targetURL := c.BaseURL + path
+	if len(params) > 0 {
+		targetURL += "?" + params.Encode()
+	}
+	return targetURL
+}
+
+// Get fetches a single object and decodes it into elt.
+func (c *Client) Get(ctx context.Context, path string, params url.Values, elt interface{}) error {
+	_, err := c.do(ctx, "GET", c.URL(path, params), nil, elt)
+	return err
+}
+
+// GetAll fetches every page of a listing and appends the results
+// to the slice that elts points to.
+func (c *Client) GetAll(ctx context.Context, path string, params url.Values, elts interface{}) error {
+	slice := reflect.ValueOf(elts)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("GetAll requires a pointer to a slice, found %T", elts)
+	}
+	slice = slice.Elem()
+
+	if c.PerPage > 0 {
+		params = copyValues(params)
+		params.Set("per_page", fmt.Sprintf("%d", c.PerPage))
+	}
+	seen := make(map[string]bool)
+	next := c.URL(path, params)
+	for pages := 0; next != ""; pages++ {
+		if pages >= MaxPages {
+			return fmt.Errorf("giving up on %s after %d pages", path, MaxPages)
+		}
+		if seen[next] {
+			return fmt.Errorf("pagination loop detected: %s was already fetched", next)
+		}
+		seen[next] = true
+
+		page := reflect.New(slice.Type())
+		var err error
+		if next, err = c.do(ctx, "GET", next, nil, page.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.AppendSlice(slice, page.Elem()))
+	}
+	return nil
+}
+
+// Post sends body as JSON and decodes the response into result (if non-nil).
+func (c *Client) Post(ctx context.Context, path string, body, result interface{}) error {
+	return c.send(ctx, "POST", path, body, result)
+}
+
+// Put sends body as JSON and decodes the response into result (if non-nil).
+func (c *Client) Put(ctx context.Context, path string, body, result interface{}) error {
+	return c.send(ctx, "PUT", path, body, result)
+}
+
+// Delete deletes the object at path and decodes the response into result (if non-nil).
+func (c *Client) Delete(ctx context.Context, path string, params url.Values, result interface{}) error {
+	_, err := c.do(ctx, "DELETE", c.URL(path, params), nil, result)
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body, result interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error JSON encoding %s body for %s: %v", method, path, err)
+	}
+	_, err = c.do(ctx, method, c.URL(path, nil), raw, result)
+	return err
+}
+
+// do performs a request and decodes the response into result (if non-nil),
+// retrying requests that fail in ways that are likely to be temporary when it is
+// safe to send them again.
+// It returns the URL of the next page named in the Link header, or "" if there is none.
+func (c *Client) do(ctx context.Context, method, targetURL string, body []byte, result interface{}) (string, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.throttle(ctx); err != nil {
+			return "", err
+		}
+		next, retry, err := c.doOnce(ctx, method, targetURL, body, result)
+		if err == nil || !retry || !retryable(method, err) || attempt >= c.MaxRetries || ctx.Err() != nil {
+			return next, err
+		}
+
+		wait := c.backoff(attempt, err)
+		c.logf("retrying %s %s in %v after error: %v", method, targetURL, wait, err)
+		if err := sleep(ctx, wait); err != nil {
+			return "", err
+		}
+	}
+}
+
+// doOnce performs a single request. retry reports whether a failure is worth trying again.
+func (c *Client) doOnce(ctx context.Context, method, targetURL string, body []byte, result interface{}) (next string, retry bool, err error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, targetURL, reader)
+	if err != nil {
+		return "", false, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Authorization", "Bearer "+c.Token)
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", true, fmt.Errorf("%s error: %v", method, err)
+	}
+	defer resp.Body.Close()
+	c.noteRateLimit(resp.Header)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := newAPIError(method, targetURL, resp)
+		return "", apiErr.temporary(), apiErr
+	}
+
+	// decode it
+	if result != nil {
+		decoder := json.NewDecoder(resp.Body)
+		if err = decoder.Decode(result); err != nil {
+			return "", false, fmt.Errorf("error decoding %s response from %s: %v", method, targetURL, err)
+		}
+	}
+
+	return nextLink(resp.Header), false, nil
+}
+
+// nextLink finds the rel="next" URL in a Link header, e.g.:
+//
+//	Link: <https://host/api/v1/x?page=2>; rel="next", <https://host/api/v1/x?page=5>; rel="last"
+func nextLink(header http.Header) string {
+	for _, value := range header["Link"] {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if len(target) < 2 || target[0] != '<' || target[len(target)-1] != '>' {
+				continue
+			}
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if param == `rel="next"` || param == "rel=next" {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func copyValues(params url.Values) url.Values {
+	out := make(url.Values)
+	for key, values := range params {
+		out[key] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// APIError is returned when Canvas responds with a non-2xx status.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+
+	// Body is the raw response body
+	Body []byte
+
+	// Messages are the error messages found in the body, if it was a Canvas JSON error
+	Messages []string
+
+	// RetryAfter is the delay requested by a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func newAPIError(method, targetURL string, resp *http.Response) *APIError {
+	e := &APIError{
+		Method:     method,
+		URL:        targetURL,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	e.Body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	// Canvas reports errors in a few shapes:
+	//   {"errors": [{"message": "..."}]}
+	//   {"errors": {"name": [{"attribute": "name", "message": "..."}]}}
+	//   {"message": "..."}
+	var parsed interface{}
+	if json.Unmarshal(e.Body, &parsed) == nil {
+		e.Messages = findMessages(parsed, nil)
+	}
+	return e
+}
+
+func findMessages(elt interface{}, messages []string) []string {
+	switch v := elt.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"message", "errors"} {
+			if msg, ok := v[key].(string); ok {
+				messages = append(messages, msg)
+			}
+		}
+		for _, child := range v {
+			messages = findMessages(child, messages)
+		}
+	case []interface{}:
+		for _, child := range v {
+			messages = findMessages(child, messages)
+		}
+	}
+	return messages
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if len(e.Messages) > 0 {
+		msg += ": " + strings.Join(e.Messages, "; ")
+	}
+	return msg
+}
@@ -0,0 +1,140 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		links []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{`<https://host/x?page=2>; rel="next"`}, "https://host/x?page=2"},
+		{[]string{`<https://host/x?page=1>; rel="current",<https://host/x?page=2>; rel="next", <https://host/x?page=5>; rel="last"`}, "https://host/x?page=2"},
+		{[]string{`<https://host/x?page=1>; rel="first"`, `<https://host/x?page=3>; rel=next`}, "https://host/x?page=3"},
+		{[]string{`<https://host/x?page=3>; per_page=10; rel="next"`}, "https://host/x?page=3"},
+		{[]string{`<https://host/x?page=1>; rel="first", <https://host/x?page=5>; rel="last"`}, ""},
+		{[]string{`https://host/x?page=2; rel="next"`}, ""},
+		{[]string{`<https://host/x?page=2>; rel="nextish"`}, ""},
+	}
+	for _, test := range tests {
+		header := http.Header{"Link": test.links}
+		if got := nextLink(header); got != test.want {
+			t.Errorf("%q: got %q, expected %q", test.links, got, test.want)
+		}
+	}
+}
+
+// pagedServer serves ids 1 through total in pages of the requested size,
+// linking each page to the next one as Canvas does
+func pagedServer(t *testing.T, total int) (*httptest.Server, *int) {
+	requests := new(int)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("got Authorization %q", got)
+		}
+		var page, perPage int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		fmt.Sscan(r.URL.Query().Get("per_page"), &perPage)
+		if page == 0 {
+			page = 1
+		}
+		if perPage == 0 {
+			perPage = 10
+		}
+		last := (total + perPage - 1) / perPage
+		link := func(n int, rel string) string {
+			return fmt.Sprintf(`<%s%s?page=%d&per_page=%d>; rel="%s"`, server.URL, r.URL.Path, n, perPage, rel)
+		}
+		links := []string{link(page, "current"), link(1, "first"), link(last, "last")}
+		if page < last {
+			links = append(links, link(page+1, "next"))
+		}
+		w.Header().Set("Link", strings.Join(links, ","))
+
+		var ids []string
+		for id := (page-1)*perPage + 1; id <= page*perPage && id <= total; id++ {
+			ids = append(ids, fmt.Sprintf(`{"id": %d}`, id))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(ids, ","))
+	}))
+	return server, requests
+}
+
+func TestGetAll(t *testing.T) {
+	tests := []struct {
+		perPage int
+		total   int
+		pages   int
+	}{
+		{100, 0, 1},
+		{100, 42, 1},
+		{10, 42, 5},
+		{7, 42, 6},
+		{0, 25, 3},
+	}
+	for _, test := range tests {
+		server, requests := pagedServer(t, test.total)
+		c := NewClient(server.URL, "token")
+		c.PerPage = test.perPage
+
+		var results []struct{ ID int }
+		err := c.GetAll(context.Background(), "/api/v1/courses/1/assignments", nil, &results)
+		server.Close()
+		if err != nil {
+			t.Errorf("per_page %d: %v", test.perPage, err)
+			continue
+		}
+		var got, want []int
+		for i, elt := range results {
+			got = append(got, elt.ID)
+			want = append(want, i+1)
+		}
+		if len(got) != test.total || !reflect.DeepEqual(got, want) {
+			t.Errorf("per_page %d: got ids %v, expected 1 through %d", test.perPage, got, test.total)
+		}
+		if *requests != test.pages {
+			t.Errorf("per_page %d: fetched %d pages, expected %d", test.perPage, *requests, test.pages)
+		}
+	}
+}
+
+func TestGetAllLoop(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// page 2 links back to page 1
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		next := map[string]string{"1": "2", "2": "1"}[page]
+		w.Header().Set("Link", fmt.Sprintf(`<%s/x?page=%s>; rel="next"`, server.URL, next))
+		w.Write([]byte(`[{"id": 1}]`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "token")
+	c.PerPage = 0
+
+	var results []struct{ ID int }
+	err := c.GetAll(context.Background(), "/x", nil, &results)
+	if err == nil || !strings.Contains(err.Error(), "pagination loop detected") {
+		t.Errorf("got error %v, expected a pagination loop", err)
+	}
+}
+
+func TestGetAllNeedsSlice(t *testing.T) {
+	c := NewClient("http://localhost", "token")
+	var result struct{ ID int }
+	if err := c.GetAll(context.Background(), "/x", nil, &result); err == nil {
+		t.Errorf("expected an error for a pointer to a struct")
+	}
+}
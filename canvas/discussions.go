@@ -0,0 +1,146 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// discussion types
+const (
+	DiscussionSideComment = "side_comment"
+	DiscussionThreaded    = "threaded"
+)
+
+// DiscussionTopic is a discussion topic. An ungraded topic is a template entry
+// of its own. A graded topic belongs to an assignment, which supplies its title,
+// message, lock date, and published state.
+type DiscussionTopic struct {
+	ID                     int    `json:"id,omitempty" yaml:"id,omitempty"`
+	Title                  string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message                string `json:"message,omitempty" yaml:"message,omitempty"`
+	DiscussionType         string `json:"discussion_type,omitempty" yaml:"discussion_type,omitempty"`
+	RequireInitialPost     bool   `json:"require_initial_post,omitempty" yaml:"require_initial_post,omitempty"`
+	PodcastEnabled         bool   `json:"podcast_enabled,omitempty" yaml:"podcast_enabled,omitempty"`
+	PodcastHasStudentPosts bool   `json:"podcast_has_student_posts,omitempty" yaml:"podcast_has_student_posts,omitempty"`
+	DelayedPostAt          *Time  `json:"delayed_post_at,omitempty" yaml:"delayed_post_at,omitempty"`
+	LockAt                 *Time  `json:"lock_at,omitempty" yaml:"lock_at,omitempty"`
+	Pinned                 bool   `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	Published              bool   `json:"published,omitempty" yaml:"published,omitempty"`
+
+	// fields that are reported but never set
+	AssignmentID int    `json:"assignment_id,omitempty" yaml:"assignment_id,omitempty"`
+	HTMLURL      string `json:"html_url,omitempty" yaml:"html_url,omitempty"`
+	PostedAt     *Time  `json:"posted_at,omitempty" yaml:"posted_at,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+func (elt *DiscussionTopic) Cleanup() {
+	elt.AssignmentID = 0
+	elt.HTMLURL = ""
+	elt.PostedAt = nil
+}
+
+func (elt *DiscussionTopic) Times() []*Time {
+	var lst []*Time
+	for _, t := range []*Time{elt.DelayedPostAt, elt.LockAt} {
+		if t != nil {
+			lst = append(lst, t)
+		}
+	}
+	return lst
+}
+
+func (elt *DiscussionTopic) ClearIDs() {
+	elt.ID = 0
+	elt.AssignmentID = 0
+}
+
+func (elt *DiscussionTopic) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Discussion: elt}})
+}
+
+// discussionBody is a topic as the API expects it when saving. The settings
+// that are true or false are always sent so that an update can turn them off.
+// Published is always sent for ungraded topics so that an unpublished topic
+// stays a draft, and never for graded ones, which follow their assignment.
+type discussionBody struct {
+	Title                  string `json:"title,omitempty"`
+	Message                string `json:"message,omitempty"`
+	DiscussionType         string `json:"discussion_type,omitempty"`
+	RequireInitialPost     bool   `json:"require_initial_post"`
+	PodcastEnabled         bool   `json:"podcast_enabled"`
+	PodcastHasStudentPosts bool   `json:"podcast_has_student_posts"`
+	DelayedPostAt          *Time  `json:"delayed_post_at,omitempty"`
+	LockAt                 *Time  `json:"lock_at,omitempty"`
+	Pinned                 bool   `json:"pinned"`
+	Published              *bool  `json:"published,omitempty"`
+}
+
+func newDiscussionBody(elt *DiscussionTopic) *discussionBody {
+	return &discussionBody{
+		Title:                  elt.Title,
+		Message:                elt.Message,
+		DiscussionType:         elt.DiscussionType,
+		RequireInitialPost:     elt.RequireInitialPost,
+		PodcastEnabled:         elt.PodcastEnabled,
+		PodcastHasStudentPosts: elt.PodcastHasStudentPosts,
+		DelayedPostAt:          elt.DelayedPostAt,
+		LockAt:                 elt.LockAt,
+		Pinned:                 elt.Pinned,
+	}
+}
+
+// GetDiscussionTopic fetches a single discussion topic.
+func (c *Client) GetDiscussionTopic(ctx context.Context, courseID, topicID int) (*DiscussionTopic, error) {
+	topic := new(DiscussionTopic)
+	path := fmt.Sprintf("/api/v1/courses/%d/discussion_topics/%d", courseID, topicID)
+	if err := c.Get(ctx, path, nil, topic); err != nil {
+		return nil, err
+	}
+	return topic, nil
+}
+
+// ListDiscussionTopics fetches every discussion topic in a course, graded or not.
+// Announcements are not included.
+func (c *Client) ListDiscussionTopics(ctx context.Context, courseID int) ([]*DiscussionTopic, error) {
+	var topics []*DiscussionTopic
+	path := fmt.Sprintf("/api/v1/courses/%d/discussion_topics", courseID)
+	if err := c.GetAll(ctx, path, nil, &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+// SaveDiscussionTopic creates an ungraded topic if it has no ID, or updates it otherwise.
+// It returns the topic as Canvas reports it after the change.
+func (c *Client) SaveDiscussionTopic(ctx context.Context, courseID int, elt *DiscussionTopic) (*DiscussionTopic, error) {
+	body := newDiscussionBody(elt)
+	published := elt.Published
+	body.Published = &published
+	result := new(DiscussionTopic)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/discussion_topics", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/discussion_topics/%d", courseID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// SaveGradedDiscussion updates the discussion settings of the topic that Canvas
+// created for a graded discussion assignment. The title, message, lock date, and
+// published state belong to the assignment, so they are not sent.
+func (c *Client) SaveGradedDiscussion(ctx context.Context, courseID, topicID int, elt *DiscussionTopic) error {
+	body := newDiscussionBody(elt)
+	body.Title = ""
+	body.Message = ""
+	body.LockAt = nil
+	path := fmt.Sprintf("/api/v1/courses/%d/discussion_topics/%d", courseID, topicID)
+	return c.Put(ctx, path, body, nil)
+}
@@ -0,0 +1,209 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Explicit records the fields of a template entry that were given as false,
+// zero, empty, or null, keyed by their JSON name. Other fields are
+// unspecified when they are zero. Explicit fields are not filled in from a
+// default and are sent on upload, so an assignment can turn off a setting
+// that its default turns on, and an update can unpublish an assignment or
+// clear a date.
+type Explicit map[string]bool
+
+// explicitKeys finds the fields of the struct that elt points to that are
+// named in keys and were decoded as zero.
+func explicitKeys(elt interface{}, keys []string) Explicit {
+	var out Explicit
+	v := reflect.ValueOf(elt).Elem()
+	for _, key := range keys {
+		if field, ok := fieldByKey(v, key); ok && isZero(field) {
+			if out == nil {
+				out = make(Explicit)
+			}
+			out[key] = true
+		}
+	}
+	return out
+}
+
+// Reset sets the explicit fields of the struct that elt points to back to zero.
+func (e Explicit) Reset(elt interface{}) {
+	v := reflect.ValueOf(elt).Elem()
+	for key := range e {
+		if field, ok := fieldByKey(v, key); ok {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// Inherit adds the explicit fields of a default that are still zero in the
+// struct that elt points to.
+func (e Explicit) Inherit(def Explicit, elt interface{}) Explicit {
+	v := reflect.ValueOf(elt).Elem()
+	for key := range def {
+		if field, ok := fieldByKey(v, key); ok && isZero(field) {
+			if e == nil {
+				e = make(Explicit)
+			}
+			e[key] = true
+		}
+	}
+	return e
+}
+
+// Without returns a copy of e without the given keys.
+func (e Explicit) Without(keys ...string) Explicit {
+	if len(e) == 0 {
+		return e
+	}
+	out := make(Explicit)
+	for key := range e {
+		out[key] = true
+	}
+	for _, key := range keys {
+		delete(out, key)
+	}
+	return out
+}
+
+// withExplicit returns a copy of the struct that elt points to as a value of
+// an equivalent struct type in which the explicit fields are not omitted when
+// they are empty. The new type has no methods, so it encodes without recursing.
+func withExplicit(elt interface{}, explicit Explicit) interface{} {
+	v := reflect.ValueOf(elt).Elem()
+	t := v.Type()
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		field := t.Field(i)
+		if explicit[jsonKey(field)] {
+			field.Tag = reflect.StructTag(strings.Replace(string(field.Tag), ",omitempty", "", -1))
+		}
+		fields[i] = field
+	}
+	out := reflect.New(reflect.StructOf(fields)).Elem()
+	for i := range fields {
+		out.Field(i).Set(v.Field(i))
+	}
+	return out.Addr().Interface()
+}
+
+// markExplicit records the explicit fields of the assignments and groups in
+// v, which has been decoded from data. API responses are decoded without it,
+// so they have no explicit fields.
+func markExplicit(v reflect.Value, data []byte) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return markExplicit(v.Elem(), data)
+
+	case reflect.Struct:
+		fields := make(map[string]json.RawMessage)
+		if json.Unmarshal(data, &fields) != nil {
+			// times, durations, and the like are not objects
+			return nil
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if raw, present := fields[jsonKey(t.Field(i))]; present && t.Field(i).PkgPath == "" {
+				if err := markExplicit(v.Field(i), raw); err != nil {
+					return err
+				}
+			}
+		}
+		if field, ok := t.FieldByName("Explicit"); ok && field.Type == reflect.TypeOf(Explicit{}) && v.CanAddr() {
+			keys, err := jsonKeys(data)
+			if err != nil {
+				return err
+			}
+			v.FieldByIndex(field.Index).Set(reflect.ValueOf(explicitKeys(v.Addr().Interface(), keys)))
+		}
+
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			if err := markExplicit(v.Index(i), items[i]); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// only values held by pointer can be updated in place
+		if v.Type().Elem().Kind() != reflect.Ptr {
+			return nil
+		}
+		items := make(map[string]json.RawMessage)
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			if raw, present := items[fmt.Sprint(key.Interface())]; present {
+				if err := markExplicit(v.MapIndex(key), raw); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonKeys returns the keys of a JSON object.
+func jsonKeys(data []byte) ([]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// yamlKeys returns the keys of a YAML mapping.
+func yamlKeys(unmarshal func(interface{}) error) ([]string, error) {
+	var fields map[string]interface{}
+	if err := unmarshal(&fields); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func jsonKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+func fieldByKey(v reflect.Value, key string) (reflect.Value, bool) {
+	if key == "" || key == "-" {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonKey(t.Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
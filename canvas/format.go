@@ -0,0 +1,228 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Format is an encoding for template files and reports.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ParseFormat checks a format name such as "json" or "yaml".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+	return "", fmt.Errorf("unknown format %q: expected json or yaml", name)
+}
+
+// FormatFor picks a format from a file name's extension, defaulting to JSON.
+func FormatFor(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return YAML
+	}
+	return JSON
+}
+
+// Encode writes elt to w in template format.
+func Encode(w io.Writer, elt interface{}, format Format) error {
+	var raw []byte
+	var err error
+	switch format {
+	case YAML:
+		raw, err = yaml.Marshal(elt)
+	default:
+		var value interface{}
+		if value, err = templateValue(reflect.ValueOf(elt)); err == nil {
+			if raw, err = json.MarshalIndent(value, "", "    "); err == nil {
+				raw = append(raw, '\n')
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s error encoding element: %v", strings.ToUpper(string(format)), err)
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+// Decode parses template data into elt. Fields that are given as false, zero,
+// or null are recorded as explicit; see Explicit.
+func Decode(data []byte, elt interface{}, format Format) error {
+	if format == YAML {
+		return yaml.Unmarshal(data, elt)
+	}
+	if err := json.Unmarshal(data, elt); err != nil {
+		return err
+	}
+	return markExplicit(reflect.ValueOf(elt), data)
+}
+
+// templateValue converts v into a value that encodes as JSON in template
+// format: times are written in local time or as class-relative dates, and the
+// explicit fields of assignments and groups are written even when empty.
+// Plain JSON encoding gives the format that the API expects.
+func templateValue(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return templateValue(v.Elem())
+	}
+
+	t := v.Type()
+	if t == reflect.TypeOf(Time{}) {
+		elt := v.Interface().(Time)
+		if elt.Relative != nil {
+			return elt.Relative.Text, nil
+		}
+		return elt.templateString(), nil
+	}
+	var explicitField reflect.StructField
+	hasExplicit := false
+	if t.Kind() == reflect.Struct {
+		explicitField, hasExplicit = t.FieldByName("Explicit")
+		hasExplicit = hasExplicit && explicitField.Type == reflect.TypeOf(Explicit{})
+	}
+	if !hasExplicit && t.Implements(marshalerType) {
+		raw, err := json.Marshal(v.Interface())
+		return json.RawMessage(raw), err
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		var explicit Explicit
+		if hasExplicit {
+			explicit = v.FieldByIndex(explicitField.Index).Interface().(Explicit)
+		}
+		var out templateObject
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			parts := strings.Split(field.Tag.Get("json"), ",")
+			key := parts[0]
+			if key == "-" {
+				continue
+			}
+			if key == "" {
+				key = field.Name
+			}
+			omitEmpty := false
+			for _, opt := range parts[1:] {
+				omitEmpty = omitEmpty || opt == "omitempty"
+			}
+			if omitEmpty && isEmpty(v.Field(i)) && !explicit[key] {
+				continue
+			}
+			value, err := templateValue(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, templateField{key, value})
+		}
+		return out, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		out := make(map[string]interface{})
+		for _, key := range v.MapKeys() {
+			value, err := templateValue(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(key.Interface())] = value
+		}
+		return out, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			value, err := templateValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	}
+
+	raw, err := json.Marshal(v.Interface())
+	return json.RawMessage(raw), err
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// templateObject is a struct converted by templateValue. It keeps the
+// fields in order, which a map would not.
+type templateObject []templateField
+
+type templateField struct {
+	key   string
+	value interface{}
+}
+
+func (obj templateObject) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, field := range obj {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, key...), ':'), value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// isEmpty reports whether a field with omitempty is left out of JSON.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
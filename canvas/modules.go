@@ -0,0 +1,279 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// module item types
+const (
+	ItemAssignment = "Assignment"
+	ItemQuiz       = "Quiz"
+	ItemDiscussion = "Discussion"
+	ItemPage       = "Page"
+	ItemFile       = "File"
+	ItemSubHeader  = "SubHeader"
+	ItemExternal   = "ExternalUrl"
+	ItemTool       = "ExternalTool"
+)
+
+// completion requirement types
+const (
+	RequireView       = "must_view"
+	RequireSubmit     = "must_submit"
+	RequireContribute = "must_contribute"
+	RequireMinScore   = "min_score"
+	RequireMarkDone   = "must_mark_done"
+)
+
+type Module struct {
+	ID                        int           `json:"id,omitempty" yaml:"id,omitempty"`
+	Name                      string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Position                  int           `json:"position,omitempty" yaml:"position,omitempty"`
+	UnlockAt                  *Time         `json:"unlock_at,omitempty" yaml:"unlock_at,omitempty"`
+	RequireSequentialProgress bool          `json:"require_sequential_progress,omitempty" yaml:"require_sequential_progress,omitempty"`
+	PrerequisiteModuleIDs     []int         `json:"prerequisite_module_ids,omitempty" yaml:"prerequisite_module_ids,omitempty,flow"`
+	Published                 bool          `json:"published,omitempty" yaml:"published,omitempty"`
+	Items                     []*ModuleItem `json:"items,omitempty" yaml:"items,omitempty"`
+
+	// Prerequisites names modules earlier in the template that must be completed first
+	Prerequisites []string `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty,flow"`
+
+	// fields that are reported but never set
+	ItemsCount int    `json:"items_count,omitempty" yaml:"items_count,omitempty"`
+	ItemsURL   string `json:"items_url,omitempty" yaml:"items_url,omitempty"`
+	State      string `json:"workflow_state,omitempty" yaml:"workflow_state,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+func (elt *Module) Cleanup() {
+	elt.ItemsCount = 0
+	elt.ItemsURL = ""
+	elt.State = ""
+	for _, item := range elt.Items {
+		item.Cleanup()
+	}
+}
+
+func (elt *Module) Times() []*Time {
+	var lst []*Time
+	if elt.UnlockAt != nil {
+		lst = append(lst, elt.UnlockAt)
+	}
+	return lst
+}
+
+func (elt *Module) ClearIDs() {
+	elt.ID = 0
+	elt.PrerequisiteModuleIDs = nil
+	for _, item := range elt.Items {
+		item.ID = 0
+		item.ModuleID = 0
+
+		// content from another course is found again by title
+		switch item.Type {
+		case ItemAssignment, ItemQuiz, ItemDiscussion:
+			if item.Title != "" {
+				item.ContentID = 0
+			}
+		}
+	}
+}
+
+func (elt *Module) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Module: elt}})
+}
+
+type ModuleItem struct {
+	ID                    int                    `json:"id,omitempty" yaml:"id,omitempty"`
+	ModuleID              int                    `json:"module_id,omitempty" yaml:"module_id,omitempty"`
+	Position              int                    `json:"position,omitempty" yaml:"position,omitempty"`
+	Title                 string                 `json:"title,omitempty" yaml:"title,omitempty"`
+	Indent                int                    `json:"indent,omitempty" yaml:"indent,omitempty"`
+	Type                  string                 `json:"type,omitempty" yaml:"type,omitempty"`
+	ContentID             int                    `json:"content_id,omitempty" yaml:"content_id,omitempty"`
+	PageURL               string                 `json:"page_url,omitempty" yaml:"page_url,omitempty"`
+	ExternalURL           string                 `json:"external_url,omitempty" yaml:"external_url,omitempty"`
+	NewTab                bool                   `json:"new_tab,omitempty" yaml:"new_tab,omitempty"`
+	CompletionRequirement *CompletionRequirement `json:"completion_requirement,omitempty" yaml:"completion_requirement,omitempty"`
+
+	// fields that are reported but never set
+	HTMLURL   string `json:"html_url,omitempty" yaml:"html_url,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	Published bool   `json:"published,omitempty" yaml:"published,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+// Positions follow the order of items in the template.
+func (elt *ModuleItem) Cleanup() {
+	elt.ID = 0
+	elt.ModuleID = 0
+	elt.Position = 0
+	elt.HTMLURL = ""
+	elt.URL = ""
+	elt.Published = false
+	if elt.CompletionRequirement != nil {
+		elt.CompletionRequirement.Completed = false
+	}
+}
+
+type CompletionRequirement struct {
+	Type      string  `json:"type,omitempty" yaml:"type,omitempty"`
+	MinScore  float64 `json:"min_score,omitempty" yaml:"min_score,omitempty"`
+	Completed bool    `json:"completed,omitempty" yaml:"completed,omitempty"`
+}
+
+// ListModules fetches every module in a course (without its items).
+func (c *Client) ListModules(ctx context.Context, courseID int) ([]*Module, error) {
+	var modules []*Module
+	path := fmt.Sprintf("/api/v1/courses/%d/modules", courseID)
+	if err := c.GetAll(ctx, path, nil, &modules); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// ListModuleItems fetches every item in a module.
+func (c *Client) ListModuleItems(ctx context.Context, courseID, moduleID int) ([]*ModuleItem, error) {
+	var items []*ModuleItem
+	path := fmt.Sprintf("/api/v1/courses/%d/modules/%d/items", courseID, moduleID)
+	if err := c.GetAll(ctx, path, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// moduleBody is a module as the API expects it when saving. The prerequisite
+// list is always sent so that an empty one removes existing prerequisites, and
+// published is always sent so that an update can unpublish a module.
+type moduleBody struct {
+	Name                      string `json:"name,omitempty"`
+	Position                  int    `json:"position,omitempty"`
+	UnlockAt                  *Time  `json:"unlock_at,omitempty"`
+	RequireSequentialProgress bool   `json:"require_sequential_progress"`
+	PrerequisiteModuleIDs     []int  `json:"prerequisite_module_ids"`
+	Published                 bool   `json:"published"`
+}
+
+func newModuleBody(elt *Module) *moduleBody {
+	module := &moduleBody{
+		Name:                      elt.Name,
+		Position:                  elt.Position,
+		UnlockAt:                  elt.UnlockAt,
+		RequireSequentialProgress: elt.RequireSequentialProgress,
+		PrerequisiteModuleIDs:     elt.PrerequisiteModuleIDs,
+		Published:                 elt.Published,
+	}
+	if module.PrerequisiteModuleIDs == nil {
+		module.PrerequisiteModuleIDs = []int{}
+	}
+	return module
+}
+
+// SaveModule creates the module if it has no ID, or updates it otherwise.
+// It returns the module as Canvas reports it after the change.
+// Items are not saved; use SyncModuleItems for those.
+func (c *Client) SaveModule(ctx context.Context, courseID int, elt *Module) (*Module, error) {
+	body := map[string]interface{}{"module": newModuleBody(elt)}
+	result := new(Module)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/modules", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/modules/%d", courseID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// SaveModuleItem creates the item if it has no ID, or updates it otherwise.
+func (c *Client) SaveModuleItem(ctx context.Context, courseID, moduleID int, elt *ModuleItem) (*ModuleItem, error) {
+	item := *elt
+	item.ModuleID = 0
+	body := map[string]interface{}{"module_item": &item}
+	result := new(ModuleItem)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/modules/%d/items", courseID, moduleID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/modules/%d/items/%d", courseID, moduleID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// DeleteModuleItem removes an item from a module. The content it refers to is not deleted.
+func (c *Client) DeleteModuleItem(ctx context.Context, courseID, moduleID, itemID int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/modules/%d/items/%d", courseID, moduleID, itemID)
+	return c.Delete(ctx, path, nil, nil)
+}
+
+// itemKey identifies the content a module item refers to, so that items can be
+// matched up without knowing their IDs
+func itemKey(elt *ModuleItem) string {
+	switch elt.Type {
+	case ItemSubHeader:
+		return elt.Type + ":" + elt.Title
+	case ItemPage:
+		return elt.Type + ":" + elt.PageURL
+	case ItemExternal:
+		return elt.Type + ":" + elt.ExternalURL
+	default:
+		return fmt.Sprintf("%s:%d", elt.Type, elt.ContentID)
+	}
+}
+
+// SyncModuleItems makes the items in a module match items, in order. Existing items
+// are matched by ID or by the content they refer to and updated; the rest are
+// created. Items in the module that are not in the list are removed from it.
+func (c *Client) SyncModuleItems(ctx context.Context, courseID, moduleID int, items []*ModuleItem) error {
+	existing, err := c.ListModuleItems(ctx, courseID, moduleID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*ModuleItem)
+	byKey := make(map[string]*ModuleItem)
+	for _, elt := range existing {
+		byID[elt.ID] = elt
+		byKey[itemKey(elt)] = elt
+	}
+
+	kept := make(map[int]bool)
+	for i, elt := range items {
+		item := *elt
+		item.Position = i + 1
+		match := byID[item.ID]
+		if match == nil {
+			match = byKey[itemKey(&item)]
+		}
+		if match != nil && !kept[match.ID] {
+			item.ID = match.ID
+			kept[match.ID] = true
+		} else {
+			item.ID = 0
+		}
+		saved, err := c.SaveModuleItem(ctx, courseID, moduleID, &item)
+		if err != nil {
+			return fmt.Errorf("saving item %q: %v", item.Title, err)
+		}
+		kept[saved.ID] = true
+	}
+
+	for _, elt := range existing {
+		if !kept[elt.ID] {
+			if err := c.DeleteModuleItem(ctx, courseID, moduleID, elt.ID); err != nil {
+				return fmt.Errorf("removing item %q: %v", elt.Title, err)
+			}
+		}
+	}
+	return nil
+}
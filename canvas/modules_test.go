@@ -0,0 +1,27 @@
+package canvas
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestModuleBody(t *testing.T) {
+	tests := []struct {
+		elt  *Module
+		want string
+	}{
+		// published and the prerequisites are always sent so an update can turn them off
+		{&Module{ID: 4, Name: "Week 1"},
+			`{"name":"Week 1","require_sequential_progress":false,"prerequisite_module_ids":[],"published":false}`},
+		{&Module{Name: "Week 2", Position: 2, PrerequisiteModuleIDs: []int{4}, Published: true},
+			`{"name":"Week 2","position":2,"require_sequential_progress":false,"prerequisite_module_ids":[4],"published":true}`},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(newModuleBody(test.elt))
+		if err != nil {
+			t.Errorf("%+v: %v", test.elt, err)
+		} else if string(got) != test.want {
+			t.Errorf("got %s, expected %s", got, test.want)
+		}
+	}
+}
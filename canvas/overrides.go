@@ -0,0 +1,169 @@
+package canvas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AssignmentOverride gives different dates to a section, a group, or a set of students.
+// Exactly one of CourseSectionID, GroupID, or StudentIDs should be set.
+type AssignmentOverride struct {
+	ID              int    `json:"id,omitempty" yaml:"id,omitempty"`
+	AssignmentID    int    `json:"assignment_id,omitempty" yaml:"assignment_id,omitempty"`
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	CourseSectionID int    `json:"course_section_id,omitempty" yaml:"course_section_id,omitempty"`
+	GroupID         int    `json:"group_id,omitempty" yaml:"group_id,omitempty"`
+	StudentIDs      []int  `json:"student_ids,omitempty" yaml:"student_ids,omitempty,flow"`
+	DueAt           *Time  `json:"due_at,omitempty" yaml:"due_at,omitempty"`
+	UnlockAt        *Time  `json:"unlock_at,omitempty" yaml:"unlock_at,omitempty"`
+	LockAt          *Time  `json:"lock_at,omitempty" yaml:"lock_at,omitempty"`
+}
+
+func (elt *AssignmentOverride) Cleanup() {
+	elt.AssignmentID = 0
+
+	// Canvas names section and group overrides after the section or group
+	if len(elt.StudentIDs) == 0 {
+		elt.Title = ""
+	}
+}
+
+// Target describes who the override applies to, e.g., "section 12" or "students 4,7".
+// Two overrides with the same target replace each other.
+func (elt *AssignmentOverride) Target() (string, error) {
+	var targets []string
+	if elt.CourseSectionID != 0 {
+		targets = append(targets, fmt.Sprintf("section %d", elt.CourseSectionID))
+	}
+	if elt.GroupID != 0 {
+		targets = append(targets, fmt.Sprintf("group %d", elt.GroupID))
+	}
+	if len(elt.StudentIDs) > 0 {
+		ids := append([]int(nil), elt.StudentIDs...)
+		sort.Ints(ids)
+		var s []string
+		for _, id := range ids {
+			s = append(s, fmt.Sprintf("%d", id))
+		}
+		targets = append(targets, "students "+strings.Join(s, ","))
+	}
+	if len(targets) != 1 {
+		return "", errors.New("an override needs exactly one of course_section_id, group_id, or student_ids")
+	}
+	return targets[0], nil
+}
+
+// ListOverrides fetches the overrides for an assignment.
+func (c *Client) ListOverrides(ctx context.Context, courseID, assignmentID int) ([]*AssignmentOverride, error) {
+	var overrides []*AssignmentOverride
+	path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d/overrides", courseID, assignmentID)
+	if err := c.GetAll(ctx, path, nil, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// overrideBody is an override as the API expects it when saving. The dates
+// are always sent, as null when unset, so that an update can clear them.
+type overrideBody struct {
+	Title           string `json:"title,omitempty"`
+	CourseSectionID int    `json:"course_section_id,omitempty"`
+	GroupID         int    `json:"group_id,omitempty"`
+	StudentIDs      []int  `json:"student_ids,omitempty"`
+	DueAt           *Time  `json:"due_at"`
+	UnlockAt        *Time  `json:"unlock_at"`
+	LockAt          *Time  `json:"lock_at"`
+}
+
+func newOverrideBody(elt *AssignmentOverride) *overrideBody {
+	return &overrideBody{
+		Title:           elt.Title,
+		CourseSectionID: elt.CourseSectionID,
+		GroupID:         elt.GroupID,
+		StudentIDs:      elt.StudentIDs,
+		DueAt:           elt.DueAt,
+		UnlockAt:        elt.UnlockAt,
+		LockAt:          elt.LockAt,
+	}
+}
+
+// SaveOverride creates the override if it has no ID, or updates it otherwise.
+func (c *Client) SaveOverride(ctx context.Context, courseID, assignmentID int, elt *AssignmentOverride) (*AssignmentOverride, error) {
+	body := map[string]*overrideBody{"assignment_override": newOverrideBody(elt)}
+	result := new(AssignmentOverride)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d/overrides", courseID, assignmentID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d/overrides/%d", courseID, assignmentID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// DeleteOverride deletes an override.
+func (c *Client) DeleteOverride(ctx context.Context, courseID, assignmentID, overrideID int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/assignments/%d/overrides/%d", courseID, assignmentID, overrideID)
+	return c.Delete(ctx, path, nil, nil)
+}
+
+// SyncOverrides makes an assignment's overrides match a list. Existing overrides
+// are matched by ID or by target and updated; others are created, and any
+// existing override that is not in the list is deleted.
+func (c *Client) SyncOverrides(ctx context.Context, courseID, assignmentID int, overrides []*AssignmentOverride) error {
+	existing, err := c.ListOverrides(ctx, courseID, assignmentID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*AssignmentOverride)
+	byTarget := make(map[string]*AssignmentOverride)
+	for _, elt := range existing {
+		byID[elt.ID] = elt
+		if target, err := elt.Target(); err == nil {
+			byTarget[target] = elt
+		}
+	}
+
+	keep := make(map[int]bool)
+	for _, elt := range overrides {
+		target, err := elt.Target()
+		if err != nil {
+			return err
+		}
+
+		// find the existing override this replaces
+		override := *elt
+		override.AssignmentID = 0
+		if _, present := byID[override.ID]; !present {
+			override.ID = 0
+			if match, present := byTarget[target]; present {
+				override.ID = match.ID
+			}
+		}
+		if keep[override.ID] && override.ID != 0 {
+			return fmt.Errorf("more than one override for %s", target)
+		}
+
+		saved, err := c.SaveOverride(ctx, courseID, assignmentID, &override)
+		if err != nil {
+			return fmt.Errorf("saving override for %s: %v", target, err)
+		}
+		keep[saved.ID] = true
+	}
+
+	for _, elt := range existing {
+		if !keep[elt.ID] {
+			if err := c.DeleteOverride(ctx, courseID, assignmentID, elt.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package canvas
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOverrideBody(t *testing.T) {
+	due := &Time{Time: time.Date(2024, time.January, 13, 6, 59, 0, 0, time.UTC)}
+	tests := []struct {
+		elt  *AssignmentOverride
+		want string
+	}{
+		// unset dates are sent as null so an update clears them
+		{&AssignmentOverride{ID: 3, CourseSectionID: 12},
+			`{"course_section_id":12,"due_at":null,"unlock_at":null,"lock_at":null}`},
+		{&AssignmentOverride{Title: "Extension", StudentIDs: []int{4, 7}, DueAt: due},
+			`{"title":"Extension","student_ids":[4,7],"due_at":"2024-01-13T06:59:00Z","unlock_at":null,"lock_at":null}`},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(newOverrideBody(test.elt))
+		if err != nil {
+			t.Errorf("%+v: %v", test.elt, err)
+		} else if string(got) != test.want {
+			t.Errorf("got %s, expected %s", got, test.want)
+		}
+	}
+}
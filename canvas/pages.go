@@ -0,0 +1,110 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// page editing roles, which are combined with commas
+const (
+	EditTeachers = "teachers"
+	EditStudents = "students"
+	EditMembers  = "members"
+	EditPublic   = "public"
+)
+
+// Page is a wiki page. Pages are identified by their URL slug, which Canvas
+// derives from the title when a page is created without one.
+type Page struct {
+	URL          string `json:"url,omitempty" yaml:"url,omitempty"`
+	Title        string `json:"title,omitempty" yaml:"title,omitempty"`
+	Body         string `json:"body,omitempty" yaml:"body,omitempty"`
+	Published    bool   `json:"published,omitempty" yaml:"published,omitempty"`
+	FrontPage    bool   `json:"front_page,omitempty" yaml:"front_page,omitempty"`
+	EditingRoles string `json:"editing_roles,omitempty" yaml:"editing_roles,omitempty"`
+
+	// BodyFile names a Markdown or HTML file that holds the body
+	BodyFile string `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+
+	// fields that are reported but never set
+	PageID    int    `json:"page_id,omitempty" yaml:"page_id,omitempty"`
+	HTMLURL   string `json:"html_url,omitempty" yaml:"html_url,omitempty"`
+	CreatedAt *Time  `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt *Time  `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+func (elt *Page) Cleanup() {
+	elt.PageID = 0
+	elt.HTMLURL = ""
+	elt.CreatedAt = nil
+	elt.UpdatedAt = nil
+}
+
+func (elt *Page) ClearIDs() {
+	elt.PageID = 0
+}
+
+func (elt *Page) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Page: elt}})
+}
+
+// pageBody is a page as the API expects it when saving. Published and
+// front_page are always sent so that either can be turned off.
+type pageBody struct {
+	URL          string `json:"url,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Body         string `json:"body"`
+	Published    bool   `json:"published"`
+	FrontPage    bool   `json:"front_page"`
+	EditingRoles string `json:"editing_roles,omitempty"`
+}
+
+// GetPage fetches a single page with its body.
+func (c *Client) GetPage(ctx context.Context, courseID int, slug string) (*Page, error) {
+	page := new(Page)
+	path := fmt.Sprintf("/api/v1/courses/%d/pages/%s", courseID, url.PathEscape(slug))
+	if err := c.Get(ctx, path, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ListPages fetches every page in a course (without their bodies).
+func (c *Client) ListPages(ctx context.Context, courseID int) ([]*Page, error) {
+	var pages []*Page
+	path := fmt.Sprintf("/api/v1/courses/%d/pages", courseID)
+	if err := c.GetAll(ctx, path, nil, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// SavePage updates the page with the given slug, or creates a new page if slug is empty.
+// It returns the page as Canvas reports it after the change. Changing the
+// title of an existing page may change its slug.
+func (c *Client) SavePage(ctx context.Context, courseID int, slug string, elt *Page) (*Page, error) {
+	body := map[string]interface{}{"wiki_page": &pageBody{
+		URL:          elt.URL,
+		Title:        elt.Title,
+		Body:         elt.Body,
+		Published:    elt.Published,
+		FrontPage:    elt.FrontPage,
+		EditingRoles: elt.EditingRoles,
+	}}
+	result := new(Page)
+	if slug == "" {
+		path := fmt.Sprintf("/api/v1/courses/%d/pages", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/pages/%s", courseID, url.PathEscape(slug))
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
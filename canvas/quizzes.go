@@ -0,0 +1,333 @@
+package canvas
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// quiz question types
+const (
+	MultipleChoice = "multiple_choice_question"
+	TrueFalse      = "true_false_question"
+	ShortAnswer    = "short_answer_question"
+	Numerical      = "numerical_question"
+	Matching       = "matching_question"
+	Essay          = "essay_question"
+)
+
+// numerical answer types
+const (
+	ExactAnswer     = "exact_answer"
+	RangeAnswer     = "range_answer"
+	PrecisionAnswer = "precision_answer"
+)
+
+// CorrectWeight is the answer weight that marks a correct answer
+const CorrectWeight = 100
+
+// Quiz is a classic quiz. Its settings that are true or false are pointers so
+// that a template can leave them unset, or turn off one that Canvas turns on.
+type Quiz struct {
+	ID                   int             `json:"id,omitempty" yaml:"id,omitempty"`
+	Title                string          `json:"title,omitempty" yaml:"title,omitempty"`
+	Description          string          `json:"description,omitempty" yaml:"description,omitempty"`
+	QuizType             string          `json:"quiz_type,omitempty" yaml:"quiz_type,omitempty"`
+	AssignmentGroupID    int             `json:"assignment_group_id,omitempty" yaml:"assignment_group_id,omitempty"`
+	TimeLimit            int             `json:"time_limit,omitempty" yaml:"time_limit,omitempty"`
+	ShuffleAnswers       *bool           `json:"shuffle_answers,omitempty" yaml:"shuffle_answers,omitempty"`
+	HideResults          string          `json:"hide_results,omitempty" yaml:"hide_results,omitempty"`
+	ShowCorrectAnswers   *bool           `json:"show_correct_answers,omitempty" yaml:"show_correct_answers,omitempty"`
+	ShowCorrectAnswersAt *Time           `json:"show_correct_answers_at,omitempty" yaml:"show_correct_answers_at,omitempty"`
+	HideCorrectAnswersAt *Time           `json:"hide_correct_answers_at,omitempty" yaml:"hide_correct_answers_at,omitempty"`
+	AllowedAttempts      int             `json:"allowed_attempts,omitempty" yaml:"allowed_attempts,omitempty"`
+	ScoringPolicy        string          `json:"scoring_policy,omitempty" yaml:"scoring_policy,omitempty"`
+	OneQuestionAtATime   *bool           `json:"one_question_at_a_time,omitempty" yaml:"one_question_at_a_time,omitempty"`
+	CantGoBack           *bool           `json:"cant_go_back,omitempty" yaml:"cant_go_back,omitempty"`
+	AccessCode           string          `json:"access_code,omitempty" yaml:"access_code,omitempty"`
+	DueAt                *Time           `json:"due_at,omitempty" yaml:"due_at,omitempty"`
+	LockAt               *Time           `json:"lock_at,omitempty" yaml:"lock_at,omitempty"`
+	UnlockAt             *Time           `json:"unlock_at,omitempty" yaml:"unlock_at,omitempty"`
+	Published            *bool           `json:"published,omitempty" yaml:"published,omitempty"`
+	Questions            []*QuizQuestion `json:"questions,omitempty" yaml:"questions,omitempty"`
+
+	// QuestionsFile names a GIFT or Aiken file of questions to add to Questions.
+	// QuestionsFormat gives its format if the file extension does not.
+	QuestionsFile   string `json:"questions_file,omitempty" yaml:"questions_file,omitempty"`
+	QuestionsFormat string `json:"questions_format,omitempty" yaml:"questions_format,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+func (elt *Quiz) Cleanup() {
+	for _, question := range elt.Questions {
+		question.Cleanup()
+	}
+}
+
+func (elt *Quiz) Times() []*Time {
+	var lst []*Time
+	for _, t := range []*Time{elt.DueAt, elt.LockAt, elt.UnlockAt, elt.ShowCorrectAnswersAt, elt.HideCorrectAnswersAt} {
+		if t != nil {
+			lst = append(lst, t)
+		}
+	}
+	return lst
+}
+
+func (elt *Quiz) ClearIDs() {
+	elt.ID = 0
+	elt.AssignmentGroupID = 0
+	for _, question := range elt.Questions {
+		question.ID = 0
+		question.QuizID = 0
+		for _, answer := range question.Answers {
+			answer.ID = 0
+		}
+	}
+}
+
+func (elt *Quiz) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Quiz: elt}})
+}
+
+type QuizQuestion struct {
+	ID                             int           `json:"id,omitempty" yaml:"id,omitempty"`
+	QuizID                         int           `json:"quiz_id,omitempty" yaml:"quiz_id,omitempty"`
+	Position                       int           `json:"position,omitempty" yaml:"position,omitempty"`
+	QuestionName                   string        `json:"question_name,omitempty" yaml:"question_name,omitempty"`
+	QuestionType                   string        `json:"question_type,omitempty" yaml:"question_type,omitempty"`
+	QuestionText                   string        `json:"question_text,omitempty" yaml:"question_text,omitempty"`
+	PointsPossible                 float64       `json:"points_possible,omitempty" yaml:"points_possible,omitempty"`
+	CorrectComments                string        `json:"correct_comments,omitempty" yaml:"correct_comments,omitempty"`
+	IncorrectComments              string        `json:"incorrect_comments,omitempty" yaml:"incorrect_comments,omitempty"`
+	NeutralComments                string        `json:"neutral_comments,omitempty" yaml:"neutral_comments,omitempty"`
+	MatchingAnswerIncorrectMatches string        `json:"matching_answer_incorrect_matches,omitempty" yaml:"matching_answer_incorrect_matches,omitempty"`
+	Answers                        []*QuizAnswer `json:"answers,omitempty" yaml:"answers,omitempty"`
+}
+
+// Cleanup clears fields that Canvas reports but that do not belong in a template.
+// Positions follow the order of questions in the template.
+func (elt *QuizQuestion) Cleanup() {
+	elt.ID = 0
+	elt.QuizID = 0
+	elt.Position = 0
+	for _, answer := range elt.Answers {
+		answer.ID = 0
+		answer.MatchID = 0
+	}
+}
+
+// QuizAnswer is an answer to a quiz question in the form that Canvas reports it.
+// An answer with weight 100 is correct. Matching questions use Left and Right,
+// and numerical questions use NumericalAnswerType with Exact and Margin, Start
+// and End, or Approximate and Precision.
+type QuizAnswer struct {
+	ID                  int     `json:"id,omitempty" yaml:"id,omitempty"`
+	Text                string  `json:"text,omitempty" yaml:"text,omitempty"`
+	Weight              float64 `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Comments            string  `json:"comments,omitempty" yaml:"comments,omitempty"`
+	Left                string  `json:"left,omitempty" yaml:"left,omitempty"`
+	Right               string  `json:"right,omitempty" yaml:"right,omitempty"`
+	MatchID             int     `json:"match_id,omitempty" yaml:"match_id,omitempty"`
+	NumericalAnswerType string  `json:"numerical_answer_type,omitempty" yaml:"numerical_answer_type,omitempty"`
+	Exact               float64 `json:"exact,omitempty" yaml:"exact,omitempty"`
+	Margin              float64 `json:"margin,omitempty" yaml:"margin,omitempty"`
+	Start               float64 `json:"start,omitempty" yaml:"start,omitempty"`
+	End                 float64 `json:"end,omitempty" yaml:"end,omitempty"`
+	Approximate         float64 `json:"approximate,omitempty" yaml:"approximate,omitempty"`
+	Precision           int     `json:"precision,omitempty" yaml:"precision,omitempty"`
+	BlankID             string  `json:"blank_id,omitempty" yaml:"blank_id,omitempty"`
+}
+
+// answerBody is an answer as the API expects it when saving a question,
+// which uses different names than the API reports
+type answerBody struct {
+	ID                  int     `json:"id,omitempty"`
+	Text                string  `json:"answer_text,omitempty"`
+	Weight              float64 `json:"answer_weight"`
+	Comments            string  `json:"answer_comments,omitempty"`
+	Left                string  `json:"answer_match_left,omitempty"`
+	Right               string  `json:"answer_match_right,omitempty"`
+	NumericalAnswerType string  `json:"numerical_answer_type,omitempty"`
+	Exact               float64 `json:"answer_exact,omitempty"`
+	Margin              float64 `json:"answer_error_margin,omitempty"`
+	Start               float64 `json:"answer_range_start,omitempty"`
+	End                 float64 `json:"answer_range_end,omitempty"`
+	Approximate         float64 `json:"answer_approximate,omitempty"`
+	Precision           int     `json:"answer_precision,omitempty"`
+	BlankID             string  `json:"blank_id,omitempty"`
+}
+
+// questionBody is a question as the API expects it when saving
+type questionBody struct {
+	QuestionName                   string        `json:"question_name,omitempty"`
+	QuestionType                   string        `json:"question_type,omitempty"`
+	QuestionText                   string        `json:"question_text,omitempty"`
+	Position                       int           `json:"position,omitempty"`
+	PointsPossible                 float64       `json:"points_possible"`
+	CorrectComments                string        `json:"correct_comments,omitempty"`
+	IncorrectComments              string        `json:"incorrect_comments,omitempty"`
+	NeutralComments                string        `json:"neutral_comments,omitempty"`
+	MatchingAnswerIncorrectMatches string        `json:"matching_answer_incorrect_matches,omitempty"`
+	Answers                        []*answerBody `json:"answers"`
+}
+
+func newQuestionBody(elt *QuizQuestion) *questionBody {
+	body := &questionBody{
+		QuestionName:                   elt.QuestionName,
+		QuestionType:                   elt.QuestionType,
+		QuestionText:                   elt.QuestionText,
+		Position:                       elt.Position,
+		PointsPossible:                 elt.PointsPossible,
+		CorrectComments:                elt.CorrectComments,
+		IncorrectComments:              elt.IncorrectComments,
+		NeutralComments:                elt.NeutralComments,
+		MatchingAnswerIncorrectMatches: elt.MatchingAnswerIncorrectMatches,
+		Answers:                        []*answerBody{},
+	}
+	for _, answer := range elt.Answers {
+		body.Answers = append(body.Answers, &answerBody{
+			ID:                  answer.ID,
+			Text:                answer.Text,
+			Weight:              answer.Weight,
+			Comments:            answer.Comments,
+			Left:                answer.Left,
+			Right:               answer.Right,
+			NumericalAnswerType: answer.NumericalAnswerType,
+			Exact:               answer.Exact,
+			Margin:              answer.Margin,
+			Start:               answer.Start,
+			End:                 answer.End,
+			Approximate:         answer.Approximate,
+			Precision:           answer.Precision,
+			BlankID:             answer.BlankID,
+		})
+	}
+	return body
+}
+
+// GetQuiz fetches a single quiz (without its questions).
+func (c *Client) GetQuiz(ctx context.Context, courseID, quizID int) (*Quiz, error) {
+	quiz := new(Quiz)
+	path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d", courseID, quizID)
+	if err := c.Get(ctx, path, nil, quiz); err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+// ListQuizQuestions fetches every question in a quiz.
+func (c *Client) ListQuizQuestions(ctx context.Context, courseID, quizID int) ([]*QuizQuestion, error) {
+	var questions []*QuizQuestion
+	path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d/questions", courseID, quizID)
+	if err := c.GetAll(ctx, path, nil, &questions); err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// SaveQuiz creates the quiz if it has no ID, or updates it otherwise.
+// It returns the quiz as Canvas reports it after the change.
+// Questions are not saved; use SyncQuizQuestions for those.
+func (c *Client) SaveQuiz(ctx context.Context, courseID int, elt *Quiz) (*Quiz, error) {
+	quiz := *elt
+	quiz.Questions = nil
+	quiz.QuestionsFile = ""
+	quiz.QuestionsFormat = ""
+	body := map[string]interface{}{"quiz": &quiz}
+	result := new(Quiz)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/quizzes", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d", courseID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// PublishQuiz publishes a quiz. Changes to the questions of a published quiz
+// are not shown to students until it is published again.
+func (c *Client) PublishQuiz(ctx context.Context, courseID, quizID int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d", courseID, quizID)
+	body := map[string]interface{}{"quiz": map[string]interface{}{"published": true}}
+	return c.Put(ctx, path, body, nil)
+}
+
+// SaveQuizQuestion creates the question if it has no ID, or updates it otherwise.
+func (c *Client) SaveQuizQuestion(ctx context.Context, courseID, quizID int, elt *QuizQuestion) (*QuizQuestion, error) {
+	body := map[string]interface{}{"question": newQuestionBody(elt)}
+	result := new(QuizQuestion)
+	if elt.ID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d/questions", courseID, quizID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d/questions/%d", courseID, quizID, elt.ID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// DeleteQuizQuestion deletes a question from a quiz.
+func (c *Client) DeleteQuizQuestion(ctx context.Context, courseID, quizID, questionID int) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/quizzes/%d/questions/%d", courseID, quizID, questionID)
+	return c.Delete(ctx, path, nil, nil)
+}
+
+// SyncQuizQuestions makes the questions in a quiz match questions, in order.
+// Questions are matched to existing ones by ID, and the rest are paired with
+// the remaining existing questions in order and updated; any left over are
+// created or deleted.
+func (c *Client) SyncQuizQuestions(ctx context.Context, courseID, quizID int, questions []*QuizQuestion) error {
+	existing, err := c.ListQuizQuestions(ctx, courseID, quizID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]bool)
+	for _, elt := range existing {
+		byID[elt.ID] = true
+	}
+
+	// questions with IDs keep them; the others take unclaimed IDs in order
+	used := make(map[int]bool)
+	for _, elt := range questions {
+		if byID[elt.ID] {
+			used[elt.ID] = true
+		}
+	}
+	var spare []int
+	for _, elt := range existing {
+		if !used[elt.ID] {
+			spare = append(spare, elt.ID)
+		}
+	}
+
+	for i, elt := range questions {
+		question := *elt
+		question.Position = i + 1
+		if !used[question.ID] {
+			question.ID = 0
+			if len(spare) > 0 {
+				question.ID, spare = spare[0], spare[1:]
+			}
+		}
+		if _, err := c.SaveQuizQuestion(ctx, courseID, quizID, &question); err != nil {
+			return fmt.Errorf("saving question %d: %v", i+1, err)
+		}
+	}
+
+	for _, id := range spare {
+		if err := c.DeleteQuizQuestion(ctx, courseID, quizID, id); err != nil {
+			return fmt.Errorf("deleting question %d: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package canvas
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitLeak is roughly how fast (in units per second) Canvas refills
+// the request quota reported in X-Rate-Limit-Remaining
+const rateLimitLeak = 10.0
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a failed request may be sent again. Requests that
+// are not idempotent are only retried after throttling, since Canvas turned
+// them away without carrying them out.
+func retryable(method string, err error) bool {
+	if idempotent(method) {
+		return true
+	}
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.throttled()
+}
+
+// temporary reports whether the request might succeed if tried again.
+func (e *APIError) temporary() bool {
+	return e.throttled() || (e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
+
+// throttled reports whether Canvas refused the request because of its rate limit.
+// Canvas signals throttling with 403 Forbidden (Rate Limit Exceeded).
+func (e *APIError) throttled() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return bytes.Contains(e.Body, []byte("Rate Limit Exceeded"))
+	}
+	return false
+}
+
+// backoff picks the delay before retry number attempt (counting from zero)
+// using exponential backoff with random jitter, unless the server asked for a
+// specific delay.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	if apiErr, ok := err.(*APIError); ok && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	limit := c.RetryWait << uint(attempt)
+	if limit <= 0 || (c.MaxRetryWait > 0 && limit > c.MaxRetryWait) {
+		limit = c.MaxRetryWait
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return limit/2 + time.Duration(rand.Int63n(int64(limit/2)+1))
+}
+
+// noteRateLimit records the quota headers from a response, e.g.:
+//
+//	X-Request-Cost: 0.0523
+//	X-Rate-Limit-Remaining: 598.92
+func (c *Client) noteRateLimit(header http.Header) {
+	remaining, err := strconv.ParseFloat(header.Get("X-Rate-Limit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateKnown = true
+	c.rateRemaining = remaining
+	if cost, err := strconv.ParseFloat(header.Get("X-Request-Cost"), 64); err == nil {
+		c.requestCost = cost
+	}
+}
+
+// throttle pauses before a request when the quota is running low, long enough
+// for Canvas to refill it to the floor plus the cost of the last request.
+func (c *Client) throttle(ctx context.Context) error {
+	c.mu.Lock()
+	deficit := c.RateLimitFloor + c.requestCost - c.rateRemaining
+	if !c.rateKnown || deficit <= 0 {
+		c.mu.Unlock()
+		return nil
+	}
+
+	// assume the quota recovers while we wait so concurrent requests do not all pause
+	c.rateRemaining += deficit
+	c.mu.Unlock()
+
+	wait := time.Duration(deficit / rateLimitLeak * float64(time.Second))
+	c.logf("rate limit remaining is low, pausing for %v", wait)
+	return sleep(ctx, wait)
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
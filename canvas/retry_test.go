@@ -0,0 +1,197 @@
+package canvas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTemporary(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusTooManyRequests, "", true},
+		{http.StatusForbidden, "403 Forbidden (Rate Limit Exceeded)\n", true},
+		{http.StatusForbidden, `{"errors": [{"message": "user not authorized to perform that action"}]}`, false},
+		{http.StatusInternalServerError, "", true},
+		{http.StatusBadGateway, "", true},
+		{http.StatusServiceUnavailable, "", true},
+		{http.StatusNotImplemented, "", false},
+		{http.StatusBadRequest, "", false},
+		{http.StatusUnauthorized, "", false},
+		{http.StatusNotFound, "", false},
+	}
+	for _, test := range tests {
+		e := &APIError{StatusCode: test.status, Body: []byte(test.body)}
+		if got := e.temporary(); got != test.want {
+			t.Errorf("%d %q: temporary() = %v, expected %v", test.status, test.body, got, test.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests}
+	serverError := &APIError{StatusCode: http.StatusBadGateway}
+	network := errors.New("connection reset by peer")
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{"GET", serverError, true},
+		{"PUT", network, true},
+		{"DELETE", throttled, true},
+		{"POST", throttled, true},
+		{"POST", &APIError{StatusCode: http.StatusForbidden, Body: []byte("403 Forbidden (Rate Limit Exceeded)")}, true},
+		{"POST", serverError, false},
+		{"POST", network, false},
+	}
+	for _, test := range tests {
+		if got := retryable(test.method, test.err); got != test.want {
+			t.Errorf("%s after %v: retryable = %v, expected %v", test.method, test.err, got, test.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{RetryWait: time.Second, MaxRetryWait: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		limit   time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			got := c.backoff(test.attempt, nil)
+			if got < test.limit/2 || got > test.limit {
+				t.Errorf("attempt %d: backoff = %v, expected between %v and %v", test.attempt, got, test.limit/2, test.limit)
+			}
+		}
+	}
+
+	// the server can ask for a specific delay
+	retryAfter := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second}
+	if got := c.backoff(0, retryAfter); got != 30*time.Second {
+		t.Errorf("backoff with Retry-After = %v, expected 30s", got)
+	}
+
+	// no waiting at all
+	if got := (&Client{}).backoff(3, nil); got != 0 {
+		t.Errorf("backoff with no RetryWait = %v, expected 0", got)
+	}
+}
+
+// flakyServer fails with each status in turn, then succeeds
+func flakyServer(statuses ...int) (*httptest.Server, *int) {
+	requests := new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if *requests <= len(statuses) {
+			if statuses[*requests-1] == http.StatusForbidden {
+				http.Error(w, "403 Forbidden (Rate Limit Exceeded)", http.StatusForbidden)
+			} else {
+				w.WriteHeader(statuses[*requests-1])
+			}
+			return
+		}
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	return server, requests
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		requests int
+		fails    bool
+	}{
+		{"success", "GET", nil, 1, false},
+		{"throttled", "GET", []int{http.StatusTooManyRequests, http.StatusForbidden}, 3, false},
+		{"server errors", "PUT", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}, 4, false},
+		{"too many failures", "GET", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, 4, true},
+		{"not temporary", "GET", []int{http.StatusNotFound}, 1, true},
+		{"not idempotent", "POST", []int{http.StatusServiceUnavailable}, 1, true},
+		{"throttled, not idempotent", "POST", []int{http.StatusTooManyRequests, http.StatusForbidden}, 3, false},
+		{"throttled, then a server error", "POST", []int{http.StatusTooManyRequests, http.StatusBadGateway}, 2, true},
+	}
+	for _, test := range tests {
+		server, requests := flakyServer(test.statuses...)
+		c := NewClient(server.URL, "token")
+		c.MaxRetries = 3
+		c.RetryWait = time.Millisecond
+		c.MaxRetryWait = time.Millisecond
+
+		var result struct{ ID int }
+		var err error
+		if test.method == "GET" {
+			err = c.Get(context.Background(), "/api/v1/x", nil, &result)
+		} else {
+			err = c.send(context.Background(), test.method, "/api/v1/x", map[string]int{}, &result)
+		}
+		server.Close()
+
+		if test.fails {
+			if _, ok := err.(*APIError); !ok {
+				t.Errorf("%s: got error %v, expected an APIError", test.name, err)
+			}
+		} else if err != nil || result.ID != 7 {
+			t.Errorf("%s: got %+v, %v", test.name, result, err)
+		}
+		if *requests != test.requests {
+			t.Errorf("%s: made %d requests, expected %d", test.name, *requests, test.requests)
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	server, requests := flakyServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	c := NewClient(server.URL, "token")
+	c.RetryWait = time.Hour
+	c.MaxRetryWait = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Get(ctx, "/api/v1/x", nil, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if *requests != 1 {
+		t.Errorf("made %d requests, expected 1", *requests)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "token")
+	c.MaxRetries = 0
+
+	err := c.Get(context.Background(), "/api/v1/x", nil, nil)
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("got error %v, expected an APIError", err)
+	}
+	if apiErr.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter = %v, expected 2m0s", apiErr.RetryAfter)
+	}
+	if got := c.backoff(0, apiErr); got != 2*time.Minute {
+		t.Errorf("backoff = %v, expected 2m0s", got)
+	}
+}
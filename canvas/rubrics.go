@@ -0,0 +1,227 @@
+package canvas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Rubric is a rubric as the rubrics API reports it.
+type Rubric struct {
+	ID                        int               `json:"id,omitempty" yaml:"id,omitempty"`
+	Title                     string            `json:"title,omitempty" yaml:"title,omitempty"`
+	PointsPossible            float64           `json:"points_possible,omitempty" yaml:"points_possible,omitempty"`
+	FreeFormCriterionComments bool              `json:"free_form_criterion_comments,omitempty" yaml:"free_form_criterion_comments,omitempty"`
+	Data                      []*RubricCriteria `json:"data,omitempty" yaml:"data,omitempty"`
+
+	// fields that are reported but never set
+	ContextID    int                  `json:"context_id,omitempty" yaml:"context_id,omitempty"`
+	ContextType  string               `json:"context_type,omitempty" yaml:"context_type,omitempty"`
+	Associations []*RubricAssociation `json:"associations,omitempty" yaml:"associations,omitempty"`
+}
+
+// UsedElsewhere reports whether a rubric belongs to something other than the
+// course, or is attached to an assignment other than the given one. Its
+// associations must have been fetched, as GetRubric does.
+func (elt *Rubric) UsedElsewhere(courseID, assignmentID int) bool {
+	if elt.ContextType != "" && (elt.ContextType != "Course" || elt.ContextID != courseID) {
+		return true
+	}
+	for _, assoc := range elt.Associations {
+		if assoc.AssociationType == "Assignment" && assoc.AssociationID != assignmentID {
+			return true
+		}
+	}
+	return false
+}
+
+// RubricAssociation attaches a rubric to an assignment (or another object).
+type RubricAssociation struct {
+	ID              int    `json:"id,omitempty" yaml:"id,omitempty"`
+	RubricID        int    `json:"rubric_id,omitempty" yaml:"rubric_id,omitempty"`
+	AssociationID   int    `json:"association_id,omitempty" yaml:"association_id,omitempty"`
+	AssociationType string `json:"association_type,omitempty" yaml:"association_type,omitempty"`
+	UseForGrading   bool   `json:"use_for_grading" yaml:"use_for_grading"`
+	HideScoreTotal  bool   `json:"hide_score_total" yaml:"hide_score_total"`
+	Purpose         string `json:"purpose,omitempty" yaml:"purpose,omitempty"`
+}
+
+type rubricResponse struct {
+	Rubric            *Rubric            `json:"rubric"`
+	RubricAssociation *RubricAssociation `json:"rubric_association"`
+}
+
+// rubricParams builds the rubric part of a create or update request. Canvas
+// expects criteria and ratings as objects keyed by position rather than lists.
+func rubricParams(title string, freeFormComments bool, criteria []*RubricCriteria) map[string]interface{} {
+	criteriaParams := make(map[string]interface{})
+	for i, criterion := range criteria {
+		ratings := make(map[string]interface{})
+		for j, rating := range criterion.Ratings {
+			ratings[fmt.Sprintf("%d", j)] = map[string]interface{}{
+				"description":      rating.Description,
+				"long_description": rating.LongDescription,
+				"points":           rating.Points,
+			}
+		}
+		criteriaParams[fmt.Sprintf("%d", i)] = map[string]interface{}{
+			"description":      criterion.Description,
+			"long_description": criterion.LongDescription,
+			"points":           criterion.Points,
+			"ratings":          ratings,
+		}
+	}
+	return map[string]interface{}{
+		"title":                        title,
+		"free_form_criterion_comments": freeFormComments,
+		"criteria":                     criteriaParams,
+	}
+}
+
+// SaveAssignmentRubric makes an assignment's rubric match asst.Rubric and
+// asst.RubricSettings. The rubric already attached to the assignment (or the
+// one named by RubricSettings.ID) is updated, unless another assignment or an
+// account shares it; otherwise a new one is created. Either way it is
+// associated with the assignment for grading. It returns the rubric ID.
+func (c *Client) SaveAssignmentRubric(ctx context.Context, courseID int, asst *Assignment) (int, error) {
+	if asst.ID == 0 {
+		return 0, errors.New("an assignment must be saved before its rubric")
+	}
+	if len(asst.Rubric) == 0 {
+		return 0, errors.New("assignment has no rubric to save")
+	}
+	settings := asst.RubricSettings
+	if settings == nil {
+		settings = new(RubricSettings)
+	}
+	title := settings.Title
+	if title == "" {
+		title = asst.Name
+	}
+
+	// find the rubric this replaces so that re-running does not create duplicates
+	rubricID := settings.ID
+	if rubricID == 0 {
+		live, err := c.GetAssignment(ctx, courseID, asst.ID)
+		if err != nil {
+			return 0, err
+		}
+		if live.RubricSettings != nil {
+			rubricID = live.RubricSettings.ID
+		}
+	}
+
+	// a shared rubric is left alone, and the assignment gets a copy of its own
+	if rubricID != 0 {
+		live, err := c.GetRubric(ctx, courseID, rubricID)
+		if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			rubricID = 0
+		} else if err != nil {
+			return 0, err
+		} else if live.UsedElsewhere(courseID, asst.ID) {
+			rubricID = 0
+		}
+	}
+
+	body := map[string]interface{}{
+		"rubric": rubricParams(title, settings.FreeFormCriterionComments, asst.Rubric),
+		"rubric_association": &RubricAssociation{
+			AssociationID:   asst.ID,
+			AssociationType: "Assignment",
+			UseForGrading:   asst.UseRubricForGrading,
+			HideScoreTotal:  settings.HideScoreTotal,
+			Purpose:         "grading",
+		},
+	}
+	return c.saveRubric(ctx, courseID, rubricID, body)
+}
+
+// saveRubric creates a rubric if rubricID is zero, or updates it otherwise.
+func (c *Client) saveRubric(ctx context.Context, courseID, rubricID int, body interface{}) (int, error) {
+	result := new(rubricResponse)
+	if rubricID == 0 {
+		path := fmt.Sprintf("/api/v1/courses/%d/rubrics", courseID)
+		if err := c.Post(ctx, path, body, result); err != nil {
+			return 0, err
+		}
+	} else {
+		path := fmt.Sprintf("/api/v1/courses/%d/rubrics/%d", courseID, rubricID)
+		if err := c.Put(ctx, path, body, result); err != nil {
+			return 0, err
+		}
+	}
+	if result.Rubric == nil {
+		return 0, errors.New("Canvas did not return the saved rubric")
+	}
+	return result.Rubric.ID, nil
+}
+
+// LibraryRubric is a named rubric in a template file. Assignments refer to it by
+// key with rubric_ref, and it is kept in the course's rubric bank so that every
+// assignment that uses it shares a single copy.
+type LibraryRubric struct {
+	Key                       string            `json:"key,omitempty" yaml:"key,omitempty"`
+	ID                        int               `json:"id,omitempty" yaml:"id,omitempty"`
+	Title                     string            `json:"title,omitempty" yaml:"title,omitempty"`
+	FreeFormCriterionComments bool              `json:"free_form_criterion_comments,omitempty" yaml:"free_form_criterion_comments,omitempty"`
+	HideScoreTotal            bool              `json:"hide_score_total,omitempty" yaml:"hide_score_total,omitempty"`
+	Criteria                  []*RubricCriteria `json:"criteria,omitempty" yaml:"criteria,omitempty"`
+}
+
+func (elt *LibraryRubric) ClearIDs() {
+	elt.ID = 0
+}
+
+func (elt *LibraryRubric) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Rubric: elt}})
+}
+
+// GetRubric fetches a rubric in a course along with its assignment associations.
+func (c *Client) GetRubric(ctx context.Context, courseID, rubricID int) (*Rubric, error) {
+	rubric := new(Rubric)
+	path := fmt.Sprintf("/api/v1/courses/%d/rubrics/%d", courseID, rubricID)
+	params := url.Values{"include[]": {"assignment_associations"}}
+	if err := c.Get(ctx, path, params, rubric); err != nil {
+		return nil, err
+	}
+	return rubric, nil
+}
+
+// ListRubrics fetches every rubric in a course's rubric bank.
+func (c *Client) ListRubrics(ctx context.Context, courseID int) ([]*Rubric, error) {
+	var rubrics []*Rubric
+	path := fmt.Sprintf("/api/v1/courses/%d/rubrics", courseID)
+	if err := c.GetAll(ctx, path, nil, &rubrics); err != nil {
+		return nil, err
+	}
+	return rubrics, nil
+}
+
+// SaveRubric creates a library rubric in the course's rubric bank if it has no
+// ID, or updates it otherwise. It returns the rubric ID.
+func (c *Client) SaveRubric(ctx context.Context, courseID int, elt *LibraryRubric) (int, error) {
+	title := elt.Title
+	if title == "" {
+		title = elt.Key
+	}
+	body := map[string]interface{}{
+		"rubric": rubricParams(title, elt.FreeFormCriterionComments, elt.Criteria),
+		"rubric_association": &RubricAssociation{
+			AssociationID:   courseID,
+			AssociationType: "Course",
+			Purpose:         "bookmark",
+		},
+	}
+	return c.saveRubric(ctx, courseID, elt.ID, body)
+}
+
+// AssociateRubric attaches a rubric that is already in the course to an assignment
+// or another object. An assignment's existing rubric association is replaced.
+func (c *Client) AssociateRubric(ctx context.Context, courseID int, assoc *RubricAssociation) error {
+	path := fmt.Sprintf("/api/v1/courses/%d/rubric_associations", courseID)
+	body := map[string]interface{}{"rubric_association": assoc}
+	return c.Post(ctx, path, body, nil)
+}
@@ -0,0 +1,178 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Series is a template entry that stands for a numbered run of nearly identical
+// assignments, such as weekly labs, e.g.:
+//
+//	{"series": {
+//	    "name": "Lab {{n}}",
+//	    "count": 14,
+//	    "start": "2014-08-29 23:59",
+//	    "step": "1 week",
+//	    "skip": [8],
+//	    "assignment": {"points_possible": 10, "description_file": "labs/lab{{n}}.md"},
+//	    "items": {"14": {"points_possible": 20}}
+//	}}
+//
+// Each generated assignment is numbered starting from First (default 1) and
+// is due Step after the one before it, whether or not that one is skipped.
+// {{n}} is replaced by the number in the name, description, and description_file.
+// Items holds fields for individual numbers that replace the shared ones.
+// Generated assignments are identified by number, so the IDs that upload
+// records in IDs stay with the same assignment if the count or skips change.
+type Series struct {
+	Name       string              `json:"name,omitempty" yaml:"name,omitempty"`
+	Count      int                 `json:"count,omitempty" yaml:"count,omitempty"`
+	First      int                 `json:"first,omitempty" yaml:"first,omitempty"`
+	Start      *Time               `json:"start,omitempty" yaml:"start,omitempty"`
+	Step       *Step               `json:"step,omitempty" yaml:"step,omitempty"`
+	Skip       []int               `json:"skip,omitempty" yaml:"skip,omitempty,flow"`
+	Assignment *Assignment         `json:"assignment,omitempty" yaml:"assignment,omitempty"`
+	Items      map[int]*Assignment `json:"items,omitempty" yaml:"items,omitempty"`
+	IDs        map[int]int         `json:"ids,omitempty" yaml:"ids,omitempty"`
+}
+
+// Numbers returns the numbers of the assignments in the series, in order.
+func (elt *Series) Numbers() []int {
+	first := elt.First
+	if first == 0 {
+		first = 1
+	}
+	skip := make(map[int]bool)
+	for _, n := range elt.Skip {
+		skip[n] = true
+	}
+	var lst []int
+	for n := first; n < first+elt.Count; n++ {
+		if !skip[n] {
+			lst = append(lst, n)
+		}
+	}
+	return lst
+}
+
+func (elt *Series) Times() []*Time {
+	var lst []*Time
+	if elt.Start != nil {
+		lst = append(lst, elt.Start)
+	}
+	if elt.Assignment != nil {
+		lst = append(lst, elt.Assignment.Times()...)
+	}
+	for _, item := range elt.Items {
+		lst = append(lst, item.Times()...)
+	}
+	return lst
+}
+
+func (elt *Series) ClearIDs() {
+	elt.IDs = nil
+	if elt.Assignment != nil {
+		elt.Assignment.ClearIDs()
+	}
+	for _, item := range elt.Items {
+		item.ClearIDs()
+	}
+}
+
+func (elt *Series) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{{Series: elt}})
+}
+
+// Step is the time between assignments in a series. It is written as a
+// number of days or weeks, e.g., "1 week" or "3 days", or as a duration like "36h".
+// Days and weeks are calendar days, so times of day stay the same across
+// daylight saving time changes.
+type Step struct {
+	Days     int
+	Duration time.Duration
+}
+
+// After returns the time k steps after t.
+func (s Step) After(t time.Time, k int) time.Time {
+	return t.AddDate(0, 0, s.Days*k).Add(s.Duration * time.Duration(k))
+}
+
+func (s Step) String() string {
+	switch {
+	case s.Days == 0:
+		return s.Duration.String()
+	case s.Duration != 0:
+		return fmt.Sprintf("%d days %v", s.Days, s.Duration)
+	case s.Days == 7:
+		return "1 week"
+	case s.Days%7 == 0:
+		return fmt.Sprintf("%d weeks", s.Days/7)
+	case s.Days == 1:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", s.Days)
+	}
+}
+
+func (s *Step) parse(text string) error {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 1 {
+		d, err := time.ParseDuration(fields[0])
+		if err != nil {
+			return fmt.Errorf("step %q: expected a number of days or weeks, or a duration", text)
+		}
+		*s = Step{Duration: d}
+		return nil
+	}
+	if len(fields) != 2 && len(fields) != 3 {
+		return fmt.Errorf("step %q: expected a number of days or weeks, or a duration", text)
+	}
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count <= 0 {
+		return fmt.Errorf("step %q: expected a positive number of days or weeks", text)
+	}
+	step := Step{}
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "day":
+		step.Days = count
+	case "week":
+		step.Days = count * 7
+	default:
+		return fmt.Errorf("step %q: expected days or weeks", text)
+	}
+	if len(fields) == 3 {
+		if step.Duration, err = time.ParseDuration(fields[2]); err != nil {
+			return fmt.Errorf("step %q: %v", text, err)
+		}
+	}
+	*s = step
+	return nil
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Step) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+func (s *Step) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	return s.parse(text)
+}
+
+func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	return s.parse(text)
+}
@@ -0,0 +1,128 @@
+package canvas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Step
+		text string
+	}{
+		{"1 week", Step{Days: 7}, "1 week"},
+		{"2 Weeks", Step{Days: 14}, "2 weeks"},
+		{"1 day", Step{Days: 1}, "1 day"},
+		{"3 days", Step{Days: 3}, "3 days"},
+		{"7 days", Step{Days: 7}, "1 week"},
+		{"36h", Step{Duration: 36 * time.Hour}, "36h0m0s"},
+		{"1 week 2h", Step{Days: 7, Duration: 2 * time.Hour}, "7 days 2h0m0s"},
+	}
+	for _, test := range tests {
+		var step Step
+		if err := step.parse(test.in); err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if step != test.want {
+			t.Errorf("%q: got %+v, expected %+v", test.in, step, test.want)
+		}
+		if step.String() != test.text {
+			t.Errorf("%q: String() = %q, expected %q", test.in, step.String(), test.text)
+		}
+
+		// the text form parses back to the same step
+		var again Step
+		if err := again.parse(step.String()); err != nil || again != step {
+			t.Errorf("%q: %q parsed back as %+v, %v", test.in, step.String(), again, err)
+		}
+	}
+}
+
+func TestStepParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "expected a number of days or weeks, or a duration"},
+		{"weekly", "expected a number of days or weeks, or a duration"},
+		{"0 weeks", "expected a positive number of days or weeks"},
+		{"-1 day", "expected a positive number of days or weeks"},
+		{"two weeks", "expected a positive number of days or weeks"},
+		{"1 month", "expected days or weeks"},
+		{"1 week 2", "missing unit"},
+		{"1 week and 2h", "expected a number of days or weeks, or a duration"},
+	}
+	for _, test := range tests {
+		var step Step
+		err := step.parse(test.in)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.in)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %q, expected it to mention %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestStepDecode(t *testing.T) {
+	var fromJSON, fromYAML Series
+	if err := Decode([]byte(`{"name": "Lab {{n}}", "step": "2 weeks"}`), &fromJSON, JSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode([]byte("name: Lab {{n}}\nstep: 2 weeks\n"), &fromYAML, YAML); err != nil {
+		t.Fatal(err)
+	}
+	for _, series := range []Series{fromJSON, fromYAML} {
+		if series.Step == nil || *series.Step != (Step{Days: 14}) {
+			t.Errorf("got step %v, expected 2 weeks", series.Step)
+		}
+	}
+	if err := Decode([]byte(`{"step": "fortnightly"}`), &fromJSON, JSON); err == nil {
+		t.Errorf("expected an error for an invalid step")
+	}
+}
+
+func TestStepAfter(t *testing.T) {
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	// daylight saving time starts on March 10, 2024
+	start := time.Date(2024, time.March, 1, 23, 59, 0, 0, denver)
+	tests := []struct {
+		step Step
+		k    int
+		want time.Time
+	}{
+		{Step{Days: 7}, 0, start},
+		{Step{Days: 7}, 1, time.Date(2024, time.March, 8, 23, 59, 0, 0, denver)},
+		{Step{Days: 7}, 2, time.Date(2024, time.March, 15, 23, 59, 0, 0, denver)},
+		{Step{Days: 1, Duration: time.Hour}, 10, time.Date(2024, time.March, 12, 9, 59, 0, 0, denver)},
+		{Step{Duration: 24 * time.Hour}, 14, time.Date(2024, time.March, 16, 0, 59, 0, 0, denver)},
+	}
+	for _, test := range tests {
+		if got := test.step.After(start, test.k); !got.Equal(test.want) {
+			t.Errorf("%v after %d steps: got %s, expected %s", test.step, test.k, got.Format(time.RFC1123), test.want.Format(time.RFC1123))
+		}
+	}
+}
+
+func TestSeriesNumbers(t *testing.T) {
+	tests := []struct {
+		series Series
+		want   []int
+	}{
+		{Series{Count: 3}, []int{1, 2, 3}},
+		{Series{Count: 4, First: 5, Skip: []int{6, 8}}, []int{5, 7}},
+		{Series{Count: 2, Skip: []int{1, 2}}, nil},
+	}
+	for _, test := range tests {
+		if got := test.series.Numbers(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v: got %v, expected %v", test.series, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Assignment struct {
+	Default                        bool                       `json:"default,omitempty" yaml:"default,omitempty"`
+	Extends                        string                     `json:"extends,omitempty" yaml:"extends,omitempty"`
+	ID                             int                        `json:"id,omitempty" yaml:"id,omitempty"`
+	Name                           string                     `json:"name,omitempty" yaml:"name,omitempty"`
+	Description                    string                     `json:"description,omitempty" yaml:"description,omitempty"`
+	DueAt                          *Time                      `json:"due_at,omitempty" yaml:"due_at,omitempty"`
+	LockAt                         *Time                      `json:"lock_at,omitempty" yaml:"lock_at,omitempty"`
+	LockAfter                      *Duration                  `json:"lock_after,omitempty" yaml:"lock_after,omitempty"`
+	UnlockAt                       *Time                      `json:"unlock_at,omitempty" yaml:"unlock_at,omitempty"`
+	UnlockBefore                   *Duration                  `json:"unlock_before,omitempty" yaml:"unlock_before,omitempty"`
+	CourseID                       int                        `json:"course_id,omitempty" yaml:"course_id,omitempty"`
+	HTMLURL                        string                     `json:"html_url,omitempty" yaml:"html_url,omitempty"`
+	AssignmentGroupID              int                        `json:"assignment_group_id,omitempty" yaml:"assignment_group_id,omitempty"`
+	AllowedExtensions              []string                   `json:"allowed_extensions,omitempty" yaml:"allowed_extensions,omitempty,flow"`
+	TurnitinEnabled                bool                       `json:"turnitin_enabled,omitempty" yaml:"turnitin_enabled,omitempty"`
+	TurnitinSettings               *TurnitinSettings          `json:"turnitin_settings,omitempty" yaml:"turnitin_settings,omitempty"`
+	GradeGroupStudentsIndividually bool                       `json:"grade_group_students_individually,omitempty" yaml:"grade_group_students_individually,omitempty"`
+	ExternalToolTagAttributes      *ExternalToolTagAttributes `json:"external_tool_tag_attributes,omitempty" yaml:"external_tool_tag_attributes,omitempty"`
+	PeerReviews                    bool                       `json:"peer_reviews,omitempty" yaml:"peer_reviews,omitempty"`
+	AutomaticPeerReviews           bool                       `json:"automatic_peer_reviews,omitempty" yaml:"automatic_peer_reviews,omitempty"`
+	PeerReviewCount                int                        `json:"peer_review_count,omitempty" yaml:"peer_review_count,omitempty"`
+	PeerReviewsAssignAt            *Time                      `json:"peer_reviews_assign_at,omitempty" yaml:"peer_reviews_assign_at,omitempty"`
+	PeerReviewsAssignAfter         *Duration                  `json:"peer_reviews_assign_after,omitempty" yaml:"peer_reviews_assign_after,omitempty"`
+	GroupCategoryID                int                        `json:"group_category_id,omitempty" yaml:"group_category_id,omitempty"`
+	NeedsGradingCount              int                        `json:"needs_grading_count,omitempty" yaml:"needs_grading_count,omitempty"`
+	HasSubmittedSubmissions        bool                       `json:"has_submitted_submissions,omitempty" yaml:"has_submitted_submissions,omitempty"`
+	Position                       int                        `json:"position,omitempty" yaml:"position,omitempty"`
+	PostToSIS                      bool                       `json:"post_to_sis,omitempty" yaml:"post_to_sis,omitempty"`
+	Muted                          bool                       `json:"muted,omitempty" yaml:"muted,omitempty"`
+	PointsPossible                 float64                    `json:"points_possible,omitempty" yaml:"points_possible,omitempty"`
+	SubmissionTypes                []string                   `json:"submission_types,omitempty" yaml:"submission_types,omitempty,flow"`
+	GradingType                    string                     `json:"grading_type,omitempty" yaml:"grading_type,omitempty"`
+	GradingStandardID              int                        `json:"grading_standard_id,omitempty" yaml:"grading_standard_id,omitempty"`
+	Published                      bool                       `json:"published,omitempty" yaml:"published,omitempty"`
+	Unpublishable                  bool                       `json:"unpublishable,omitempty" yaml:"unpublishable,omitempty"`
+	OnlyVisibleToOverrides         bool                       `json:"only_visible_to_overrides,omitempty" yaml:"only_visible_to_overrides,omitempty"`
+	LockedForUser                  bool                       `json:"locked_for_user,omitempty" yaml:"locked_for_user,omitempty"`
+	LockInfo                       string                     `json:"lock_info,omitempty" yaml:"lock_info,omitempty"`
+	LockExplanation                string                     `json:"lock_explanation,omitempty" yaml:"lock_explanation,omitempty"`
+	QuizID                         int                        `json:"quiz_id,omitempty" yaml:"quiz_id,omitempty"`
+	AnonymousSubmissions           bool                       `json:"anonymous_submissions,omitempty" yaml:"anonymous_submissions,omitempty"`
+	DiscussionTopic                *DiscussionTopic           `json:"discussion_topic,omitempty" yaml:"discussion_topic,omitempty"`
+	FreezeOnCopy                   bool                       `json:"freeze_on_copy,omitempty" yaml:"freeze_on_copy,omitempty"`
+	Frozen                         bool                       `json:"frozen,omitempty" yaml:"frozen,omitempty"`
+	FrozenAttributes               []string                   `json:"frozen_attributes,omitempty" yaml:"frozen_attributes,omitempty"`
+	Submission                     *Submission                `json:"submission,omitempty" yaml:"submission,omitempty"`
+	UseRubricForGrading            bool                       `json:"use_rubric_for_grading,omitempty" yaml:"use_rubric_for_grading,omitempty"`
+	RubricSettings                 *RubricSettings            `json:"rubric_settings,omitempty" yaml:"rubric_settings,omitempty"`
+	Rubric                         []*RubricCriteria          `json:"rubric,omitempty" yaml:"rubric,omitempty"`
+	RubricRef                      string                     `json:"rubric_ref,omitempty" yaml:"rubric_ref,omitempty"`
+	Overrides                      []*AssignmentOverride      `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+
+	// DescriptionFile names a Markdown or HTML file that holds the description
+	DescriptionFile string `json:"description_file,omitempty" yaml:"description_file,omitempty"`
+
+	// Library is the library rubric named by RubricRef once the template is expanded
+	Library *LibraryRubric `json:"-" yaml:"-"`
+
+	// Explicit records the fields that the template gives as false, zero, or null
+	Explicit Explicit `json:"-" yaml:"-"`
+}
+
+func (elt Assignment) MarshalJSON() ([]byte, error) {
+	type plain Assignment
+	if len(elt.Explicit) == 0 {
+		return json.Marshal((*plain)(&elt))
+	}
+	return json.Marshal(withExplicit((*plain)(&elt), elt.Explicit))
+}
+
+func (elt Assignment) MarshalYAML() (interface{}, error) {
+	type plain Assignment
+	if len(elt.Explicit) == 0 {
+		return (*plain)(&elt), nil
+	}
+	return withExplicit((*plain)(&elt), elt.Explicit), nil
+}
+
+func (elt *Assignment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Assignment
+	if err := unmarshal((*plain)(elt)); err != nil {
+		return err
+	}
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	elt.Explicit = explicitKeys(elt, keys)
+	return nil
+}
+
+func (elt *Assignment) Cleanup() {
+	if !elt.TurnitinEnabled {
+		elt.TurnitinSettings = nil
+	}
+	if !elt.PeerReviews {
+		elt.AutomaticPeerReviews = false
+		elt.PeerReviewCount = 0
+		elt.PeerReviewsAssignAt = nil
+	}
+	elt.HTMLURL = ""
+	elt.Unpublishable = false
+	for _, override := range elt.Overrides {
+		override.Cleanup()
+	}
+	if topic := elt.DiscussionTopic; topic != nil {
+		// the title, message, lock date, and published state repeat the assignment's
+		topic.Cleanup()
+		topic.ID = 0
+		topic.Title = ""
+		topic.Message = ""
+		topic.LockAt = nil
+		topic.Published = false
+	}
+	/*
+		if elt.DueAt != nil && elt.LockAt != nil {
+			gap := Duration{elt.LockAt.Sub(*elt.DueAt)}
+			elt.LockAfter = &gap
+		}
+		if elt.DueAt != nil && elt.UnlockAt != nil {
+			gap := Duration{elt.DueAt.Sub(*elt.UnlockAt)}
+			elt.UnlockBefore = &gap
+		}
+		if elt.DueAt != nil && elt.PeerReviewsAssignAt != nil {
+			gap := Duration{elt.PeerReviewsAssignAt.Sub(*elt.DueAt)}
+			elt.PeerReviewsAssignAfter = &gap
+		}
+	*/
+}
+
+// Times returns the timestamps that are set in the assignment, so they can be adjusted in place.
+func (elt *Assignment) Times() []*Time {
+	var times []*Time
+	for _, t := range []*Time{elt.DueAt, elt.LockAt, elt.UnlockAt, elt.PeerReviewsAssignAt} {
+		if t != nil {
+			times = append(times, t)
+		}
+	}
+	if elt.DiscussionTopic != nil {
+		times = append(times, elt.DiscussionTopic.Times()...)
+	}
+	for _, override := range elt.Overrides {
+		for _, t := range []*Time{override.DueAt, override.UnlockAt, override.LockAt} {
+			if t != nil {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// ClearIDs removes the IDs that tie the assignment to a particular course.
+func (elt *Assignment) ClearIDs() {
+	elt.ID = 0
+	elt.CourseID = 0
+	elt.AssignmentGroupID = 0
+	elt.GroupCategoryID = 0
+	elt.QuizID = 0
+	elt.HTMLURL = ""
+	if elt.RubricSettings != nil {
+		elt.RubricSettings.ID = 0
+	}
+	if elt.DiscussionTopic != nil {
+		elt.DiscussionTopic.ClearIDs()
+	}
+	for _, override := range elt.Overrides {
+		override.ID = 0
+		override.AssignmentID = 0
+	}
+}
+
+func (elt *Assignment) Clone() (*Assignment, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(elt); err != nil {
+		return nil, fmt.Errorf("error gob encoding assignment: %v", err)
+	}
+	decoder := gob.NewDecoder(&buf)
+	clone := new(Assignment)
+	if err := decoder.Decode(clone); err != nil {
+		return nil, fmt.Errorf("error gob decoding assignment: %v", err)
+	}
+	return clone, nil
+}
+
+func (elt *Assignment) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{AssignmentOrGroup{Assignment: elt}})
+}
+
+type ExternalToolTagAttributes struct {
+	URL            string `json:"url,omitempty" yaml:"url,omitempty"`
+	NewTab         bool   `json:"new_tab" yaml:"new_tab"`
+	ResourceLinkID string `json:"resource_link_id,omitempty" yaml:"resource_link_id,omitempty"`
+}
+
+type RubricSettings struct {
+	ID                        int     `json:"id,omitempty" yaml:"id,omitempty"`
+	Title                     string  `json:"title,omitempty" yaml:"title,omitempty"`
+	PointsPossible            float64 `json:"points_possible,omitempty" yaml:"points_possible,omitempty"`
+	FreeFormCriterionComments bool    `json:"free_form_criterion_comments,omitempty" yaml:"free_form_criterion_comments,omitempty"`
+	HideScoreTotal            bool    `json:"hide_score_total,omitempty" yaml:"hide_score_total,omitempty"`
+}
+
+type RubricRating struct {
+	Points          float64 `json:"points,omitempty" yaml:"points,omitempty"`
+	ID              string  `json:"id,omitempty" yaml:"id,omitempty"`
+	Description     string  `json:"description,omitempty" yaml:"description,omitempty"`
+	LongDescription string  `json:"long_description,omitempty" yaml:"long_description,omitempty"`
+}
+
+type RubricCriteria struct {
+	Points          float64         `json:"points,omitempty" yaml:"points,omitempty"`
+	ID              string          `json:"id,omitempty" yaml:"id,omitempty"`
+	Description     string          `json:"description,omitempty" yaml:"description,omitempty"`
+	LongDescription string          `json:"long_description,omitempty" yaml:"long_description,omitempty"`
+	Ratings         []*RubricRating `json:"ratings,omitempty" yaml:"ratings,omitempty"`
+}
+
+type TurnitinSettings struct {
+	OriginalityReportVisibility string  `json:"originality_report_visibility,omitempty" yaml:"originality_report_visibility,omitempty"`
+	SPaperCheck                 bool    `json:"s_paper_check,omitempty" yaml:"s_paper_check,omitempty"`
+	InternetCheck               bool    `json:"internet_check,omitempty" yaml:"internet_check,omitempty"`
+	JournalCheck                bool    `json:"journal_check,omitempty" yaml:"journal_check,omitempty"`
+	ExcludeBiblio               bool    `json:"exclude_biblio,omitempty" yaml:"exclude_biblio,omitempty"`
+	ExcludeQuoted               bool    `json:"exclude_quoted,omitempty" yaml:"exclude_quoted,omitempty"`
+	ExcludeSmallMatchesType     string  `json:"exclude_small_matches_type,omitempty" yaml:"exclude_small_matches_type,omitempty"`
+	ExcludeSmallMatchesValue    float64 `json:"exclude_small_matches_value,omitempty" yaml:"exclude_small_matches_value,omitempty"`
+}
+
+type Submission struct {
+}
+
+// AssignmentGroup is a group of assignments. In a template, Default holds
+// fields that apply to every assignment in the group.
+type AssignmentGroup struct {
+	Default     *Assignment   `json:"default,omitempty" yaml:"default,omitempty"`
+	ID          int           `json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Position    int           `json:"position,omitempty" yaml:"position,omitempty"`
+	GroupWeight float64       `json:"group_weight,omitempty" yaml:"group_weight,omitempty"`
+	Assignments []*Assignment `json:"assignments,omitempty" yaml:"assignments,omitempty"`
+	Rules       *GradingRules `json:"rules,omitempty" yaml:"rules,omitempty"`
+
+	// Explicit records the fields that the template gives as false, zero, or null
+	Explicit Explicit `json:"-" yaml:"-"`
+}
+
+func (elt AssignmentGroup) MarshalJSON() ([]byte, error) {
+	type plain AssignmentGroup
+	if len(elt.Explicit) == 0 {
+		return json.Marshal((*plain)(&elt))
+	}
+	return json.Marshal(withExplicit((*plain)(&elt), elt.Explicit))
+}
+
+func (elt AssignmentGroup) MarshalYAML() (interface{}, error) {
+	type plain AssignmentGroup
+	if len(elt.Explicit) == 0 {
+		return (*plain)(&elt), nil
+	}
+	return withExplicit((*plain)(&elt), elt.Explicit), nil
+}
+
+func (elt *AssignmentGroup) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AssignmentGroup
+	if err := unmarshal((*plain)(elt)); err != nil {
+		return err
+	}
+	keys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	elt.Explicit = explicitKeys(elt, keys)
+	return nil
+}
+
+func (elt *AssignmentGroup) Cleanup() {
+	for _, asst := range elt.Assignments {
+		asst.Cleanup()
+	}
+	if elt.Rules != nil && elt.Rules.DropLowest == 0 && elt.Rules.DropHighest == 0 && len(elt.Rules.NeverDrop) == 0 {
+		elt.Rules = nil
+	}
+}
+
+// Times returns the timestamps that are set in the group's default and assignments.
+func (elt *AssignmentGroup) Times() []*Time {
+	var times []*Time
+	if elt.Default != nil {
+		times = append(times, elt.Default.Times()...)
+	}
+	for _, asst := range elt.Assignments {
+		times = append(times, asst.Times()...)
+	}
+	return times
+}
+
+// ClearIDs removes the IDs that tie the group and its assignments to a particular course.
+func (elt *AssignmentGroup) ClearIDs() {
+	elt.ID = 0
+	if elt.Default != nil {
+		elt.Default.ClearIDs()
+	}
+	for _, asst := range elt.Assignments {
+		asst.ClearIDs()
+	}
+	if elt.Rules != nil {
+		elt.Rules.NeverDrop = nil
+	}
+}
+
+func (elt *AssignmentGroup) Dump(w io.Writer) error {
+	return Dump(w, []AssignmentOrGroup{AssignmentOrGroup{Group: elt}})
+}
+
+type GradingRules struct {
+	DropLowest  int   `json:"drop_lowest,omitempty" yaml:"drop_lowest,omitempty"`
+	DropHighest int   `json:"drop_highest,omitempty" yaml:"drop_highest,omitempty"`
+	NeverDrop   []int `json:"never_drop,omitempty" yaml:"never_drop,omitempty"`
+}
+
+type AssignmentOrGroup struct {
+	Assignment *Assignment      `json:"assignment,omitempty" yaml:"assignment,omitempty"`
+	Group      *AssignmentGroup `json:"assignment_group,omitempty" yaml:"assignment_group,omitempty"`
+	Calendar   *Calendar        `json:"calendar,omitempty" yaml:"calendar,omitempty"`
+	Rubric     *LibraryRubric   `json:"rubric,omitempty" yaml:"rubric,omitempty"`
+	Module     *Module          `json:"module,omitempty" yaml:"module,omitempty"`
+	Quiz       *Quiz            `json:"quiz,omitempty" yaml:"quiz,omitempty"`
+	Discussion *DiscussionTopic `json:"discussion,omitempty" yaml:"discussion,omitempty"`
+	Page       *Page            `json:"page,omitempty" yaml:"page,omitempty"`
+	Series     *Series          `json:"series,omitempty" yaml:"series,omitempty"`
+
+	// Include names another template file whose entries go in place of this one.
+	// A relative name is relative to the directory of the including file.
+	Include string `json:"include,omitempty" yaml:"include,omitempty"`
+
+	// Source is the template file that the entry was read from
+	Source string `json:"-" yaml:"-"`
+}
+
+func (elt *AssignmentOrGroup) Times() []*Time {
+	if elt.Group != nil {
+		return elt.Group.Times()
+	} else if elt.Assignment != nil {
+		return elt.Assignment.Times()
+	} else if elt.Module != nil {
+		return elt.Module.Times()
+	} else if elt.Quiz != nil {
+		return elt.Quiz.Times()
+	} else if elt.Discussion != nil {
+		return elt.Discussion.Times()
+	} else if elt.Series != nil {
+		return elt.Series.Times()
+	}
+	return nil
+}
+
+func (elt *AssignmentOrGroup) ClearIDs() {
+	if elt.Group != nil {
+		elt.Group.ClearIDs()
+	} else if elt.Assignment != nil {
+		elt.Assignment.ClearIDs()
+	} else if elt.Rubric != nil {
+		elt.Rubric.ClearIDs()
+	} else if elt.Module != nil {
+		elt.Module.ClearIDs()
+	} else if elt.Quiz != nil {
+		elt.Quiz.ClearIDs()
+	} else if elt.Discussion != nil {
+		elt.Discussion.ClearIDs()
+	} else if elt.Page != nil {
+		elt.Page.ClearIDs()
+	} else if elt.Series != nil {
+		elt.Series.ClearIDs()
+	}
+}
+
+func (elt *AssignmentOrGroup) Dump(w io.Writer) error {
+	if elt.Group != nil {
+		return elt.Group.Dump(w)
+	} else if elt.Assignment != nil {
+		return elt.Assignment.Dump(w)
+	} else if elt.Rubric != nil {
+		return elt.Rubric.Dump(w)
+	} else if elt.Module != nil {
+		return elt.Module.Dump(w)
+	} else if elt.Quiz != nil {
+		return elt.Quiz.Dump(w)
+	} else if elt.Discussion != nil {
+		return elt.Discussion.Dump(w)
+	} else if elt.Page != nil {
+		return elt.Page.Dump(w)
+	} else if elt.Series != nil {
+		return elt.Series.Dump(w)
+	}
+	return errors.New("AssignmentOrGroup with no assignment, group, rubric, module, quiz, discussion, page, or series")
+}
+
+// Dump writes elt to w as indented JSON in template format.
+func Dump(w io.Writer, elt interface{}) error {
+	return Encode(w, elt, JSON)
+}
+
+// Time is a timestamp that can also be written in template files as
+// "2006-01-02 15:04:05", as a bare date, or as a bare time of day.
+// In templates it may also be relative to the class schedule, e.g.,
+// "week 5 thu" or "session 12 + 2h"; see ParseRelative.
+type Time struct {
+	time.Time
+
+	// Relative is set for a class-relative date that has not been resolved yet
+	Relative *RelativeDate
+}
+
+// MarshalJSON writes the time in the format that the API expects.
+// Encode writes it in template format instead.
+func (elt Time) MarshalJSON() ([]byte, error) {
+	if elt.Relative != nil {
+		return nil, fmt.Errorf("relative date %q has not been resolved", elt.Relative.Text)
+	}
+	return []byte(elt.UTC().Format(`"` + time.RFC3339Nano + `"`)), nil
+}
+
+func (elt Time) MarshalYAML() (interface{}, error) {
+	if elt.Relative != nil {
+		return elt.Relative.Text, nil
+	}
+	return elt.templateString(), nil
+}
+
+// templateString formats the time in local time, leaving out the
+// date or time of day if it is zero
+func (elt Time) templateString() string {
+	t := elt.Local()
+	if elt.IsTimeOfDay() {
+		return t.Format("15:04:05")
+	}
+	hour, minute, second, ns := t.Hour(), t.Minute(), t.Second(), t.Nanosecond()
+	if hour == 0 && minute == 0 && second == 0 && ns == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// IsZero reports whether the time is unset. A relative date is never zero.
+func (elt Time) IsZero() bool {
+	return elt.Relative == nil && elt.Time.IsZero()
+}
+
+// IsTimeOfDay reports whether the time was written as a bare time of day with no date.
+func (elt Time) IsTimeOfDay() bool {
+	year, month, day := elt.Local().Date()
+	return elt.Relative == nil && year == 0 && month == time.January && day == 1
+}
+
+func (elt *Time) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return elt.parse(s)
+}
+
+func (elt *Time) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return elt.parse(s)
+}
+
+func (elt *Time) parse(s string) error {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			*elt = Time{Time: t}
+			return nil
+		}
+	}
+	if IsRelative(s) {
+		rel, err := ParseRelative(s)
+		if err != nil {
+			return err
+		}
+		*elt = Time{Relative: rel}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	*elt = Time{Time: t}
+	return err
+}
+
+// Duration is a time.Duration written as a string such as "48h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	elt, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = elt
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if strings.HasPrefix(s, `"`) {
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, `"`) {
+		s = s[:len(s)-1]
+	}
+	if elt, err := time.ParseDuration(s); err != nil {
+		return err
+	} else {
+		d.Duration = elt
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultEndpoint is used when neither a profile nor the environment names a Canvas instance
+const defaultEndpoint = "https://dixie.instructure.com"
+
+// Config is the contents of the config file, e.g.:
+//
+//	{
+//	    "default_profile": "prod",
+//	    "profiles": {
+//	        "prod": {"url": "https://dixie.instructure.com", "token_env": "DIXIE_TOKEN", "course": 12345},
+//	        "beta": {"url": "https://dixie.beta.instructure.com", "token_file": "~/.canvas/beta-token"}
+//	    }
+//	}
+type Config struct {
+	DefaultProfile string              `json:"default_profile,omitempty" yaml:"default_profile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// Profile describes one Canvas instance and how to authenticate to it.
+// The token is taken from the first of Token, TokenEnv, or TokenFile that is set.
+type Profile struct {
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	Token     string `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenEnv  string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	TokenFile string `json:"token_file,omitempty" yaml:"token_file,omitempty"`
+	Course    int    `json:"course,omitempty" yaml:"course,omitempty"`
+}
+
+// configPath returns the config file location: $CANVAS_CONFIG,
+// or ~/.canvasassignments.json if that is not set.
+func configPath() string {
+	if path := os.Getenv("CANVAS_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".canvasassignments.json")
+}
+
+func readConfig(filename string) *Config {
+	config := new(Config)
+	if filename == "" {
+		return config
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) && os.Getenv("CANVAS_CONFIG") == "" {
+		// the default config file is optional
+		return config
+	}
+	if err != nil {
+		log.Fatalf("Failed to read config file %s: %v", filename, err)
+	}
+	if err = json.Unmarshal(contents, config); err != nil {
+		log.Fatalf("Error parsing config file %s: %v", filename, err)
+	}
+	return config
+}
+
+// loadProfile picks a profile from the config file and applies environment overrides.
+// The profile is chosen by name, then $CANVAS_PROFILE, then the config file's default.
+// $CANVAS_URL, $CANVAS_TOKEN, and $CANVAS_COURSE override the matching profile fields.
+func loadProfile(name string) *Profile {
+	filename := configPath()
+	config := readConfig(filename)
+
+	if name == "" {
+		name = os.Getenv("CANVAS_PROFILE")
+	}
+	if name == "" {
+		name = config.DefaultProfile
+	}
+
+	profile := new(Profile)
+	if name != "" {
+		p, present := config.Profiles[name]
+		if !present {
+			log.Fatalf("No profile named %q in config file %s", name, filename)
+		}
+		*profile = *p
+	}
+
+	// find the token
+	switch {
+	case profile.Token != "":
+	case profile.TokenEnv != "":
+		profile.Token = os.Getenv(profile.TokenEnv)
+		if profile.Token == "" {
+			log.Fatalf("Profile %q expects a token in the %s environment variable", name, profile.TokenEnv)
+		}
+	case profile.TokenFile != "":
+		profile.Token = readTokenFile(profile.TokenFile)
+	}
+
+	// environment overrides
+	if s := os.Getenv("CANVAS_URL"); s != "" {
+		profile.URL = s
+	}
+	if s := os.Getenv("CANVAS_TOKEN"); s != "" {
+		profile.Token = s
+	}
+	if s := os.Getenv("CANVAS_COURSE"); s != "" {
+		course, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatalf("Invalid CANVAS_COURSE value %q: %v", s, err)
+		}
+		profile.Course = course
+	}
+
+	if profile.URL == "" {
+		profile.URL = defaultEndpoint
+	}
+	profile.URL = strings.TrimSuffix(profile.URL, "/")
+	if profile.Token == "" {
+		log.Fatalf("No Canvas token found: set CANVAS_TOKEN or configure a profile in %s", filename)
+	}
+
+	return profile
+}
+
+func readTokenFile(filename string) string {
+	if strings.HasPrefix(filename, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatalf("Unable to find home directory for token file %s: %v", filename, err)
+		}
+		filename = filepath.Join(home, filename[2:])
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Fatalf("Failed to read token file %s: %v", filename, err)
+	}
+	return strings.TrimSpace(string(contents))
+}
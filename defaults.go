@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/imdario/mergo"
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// defaultSet holds the named defaults in a template file, i.e., default
+// assignment entries that have a name. An assignment or another default
+// can use one with "extends", wherever it appears in the file.
+type defaultSet struct {
+	cal       *canvas.Calendar
+	named     map[string]*canvas.Assignment
+	resolved  map[string]*canvas.Assignment
+	resolving []string
+}
+
+// collectDefaults finds the named defaults in a list of entries.
+func collectDefaults(cal *canvas.Calendar, entries []canvas.AssignmentOrGroup) (*defaultSet, error) {
+	set := &defaultSet{
+		cal:      cal,
+		named:    make(map[string]*canvas.Assignment),
+		resolved: make(map[string]*canvas.Assignment),
+	}
+	for _, aorg := range entries {
+		asst := aorg.Assignment
+		if asst == nil || !asst.Default || asst.Name == "" {
+			continue
+		}
+		if set.named[asst.Name] != nil {
+			return nil, fmt.Errorf("more than one default named %q", asst.Name)
+		}
+		set.named[asst.Name] = asst
+	}
+	return set, nil
+}
+
+// lookup returns the named default with everything it extends merged in.
+func (set *defaultSet) lookup(name string) (*canvas.Assignment, error) {
+	if def := set.resolved[name]; def != nil {
+		return def, nil
+	}
+	raw := set.named[name]
+	if raw == nil {
+		return nil, fmt.Errorf("no default named %q", name)
+	}
+	for i, elt := range set.resolving {
+		if elt == name {
+			cycle := append(append([]string{}, set.resolving[i:]...), name)
+			return nil, fmt.Errorf("defaults extend each other in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	set.resolving = append(set.resolving, name)
+	defer func() { set.resolving = set.resolving[:len(set.resolving)-1] }()
+
+	def, err := set.extend(raw)
+	if err != nil {
+		return nil, err
+	}
+	set.resolved[name] = def
+	return def, nil
+}
+
+// extend returns a copy of a default entry, cleared of the values that do not
+// belong in a default, with the named default that it extends merged in.
+func (set *defaultSet) extend(raw *canvas.Assignment) (*canvas.Assignment, error) {
+	// times must be resolved before cloning, which only keeps absolute times
+	if err := resolveTimes(set.cal, "default", raw.Name, raw.Times()); err != nil {
+		return nil, err
+	}
+	def, err := raw.Clone()
+	if err != nil {
+		return nil, err
+	}
+	def.Default = false
+	def.ID = 0
+	def.Name = ""
+	def.HTMLURL = ""
+	def.Position = 0
+
+	if def.Extends == "" {
+		return def, nil
+	}
+	if set.named[def.Extends] == nil && raw.Name != "" {
+		return nil, fmt.Errorf("default %q: no default named %q", raw.Name, def.Extends)
+	}
+	parent, err := set.lookup(def.Extends)
+	if err != nil {
+		return nil, err
+	}
+	def.Extends = ""
+	mergeDefault(def, parent)
+	return def, nil
+}
+
+// withGroupDefault returns def with the default of the current group merged
+// under it. Either may be nil.
+func withGroupDefault(def, groupDefault *canvas.Assignment) (*canvas.Assignment, error) {
+	if def == nil || groupDefault == nil {
+		if def == nil {
+			return groupDefault, nil
+		}
+		return def, nil
+	}
+	out, err := def.Clone()
+	if err != nil {
+		return nil, err
+	}
+	mergeDefault(out, groupDefault)
+	return out, nil
+}
+
+// mergeDefault fills in the fields of asst that are not set from a default.
+// Fields that asst gives explicitly as false, zero, or null are left alone.
+// Relative times are left for the caller to apply.
+func mergeDefault(asst, def *canvas.Assignment) {
+	// mergo merges maps too, so keep a copy of the fields asst gives explicitly
+	explicit := asst.Explicit.Without()
+	inheritOverrides := asst.Overrides == nil
+	ownRubric, ownRubricRef := len(asst.Rubric) > 0, asst.RubricRef != ""
+	ownDescription, ownDescriptionFile := asst.Description != "", asst.DescriptionFile != ""
+	mergo.Merge(asst, def)
+
+	// a rubric or rubric_ref in the assignment replaces either one from the default
+	if ownRubricRef {
+		asst.Rubric = nil
+		asst.RubricSettings = nil
+	} else if ownRubric {
+		asst.RubricRef = ""
+	}
+
+	// so does a description or description_file
+	if ownDescriptionFile {
+		asst.Description = ""
+	} else if ownDescription {
+		asst.DescriptionFile = ""
+	}
+
+	// overrides from the default are adjusted per assignment, so copy them
+	if inheritOverrides {
+		asst.Overrides = copyOverrides(asst.Overrides)
+	}
+
+	// so is a discussion topic, which also records the topic ID on upload
+	if asst.DiscussionTopic != nil {
+		topic := *asst.DiscussionTopic
+		asst.DiscussionTopic = &topic
+	}
+
+	// apply default timestamps
+	asst.DueAt = mergeDates(def.DueAt, asst.DueAt)
+	asst.LockAt = mergeDates(def.LockAt, asst.LockAt)
+	asst.UnlockAt = mergeDates(def.UnlockAt, asst.UnlockAt)
+	asst.PeerReviewsAssignAt = mergeDates(def.PeerReviewsAssignAt, asst.PeerReviewsAssignAt)
+
+	// explicit fields in asst win, and explicit fields in the default still count
+	explicit.Reset(asst)
+	asst.Explicit = explicit.Inherit(def.Explicit, asst)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// decodeAssignment decodes a template assignment, recording its explicit fields
+func decodeAssignment(t *testing.T, text string, format canvas.Format) *canvas.Assignment {
+	asst := new(canvas.Assignment)
+	if err := canvas.Decode([]byte(text), asst, format); err != nil {
+		t.Fatalf("%q: %v", text, err)
+	}
+	return asst
+}
+
+func TestMergeDefaultExplicit(t *testing.T) {
+	def := `{"published": true, "points_possible": 10, "peer_reviews": false, "due_at": "2024-01-12 23:59:00", "grading_type": "points"}`
+	tests := []struct {
+		name   string
+		format canvas.Format
+		asst   string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "inherits everything",
+			format: canvas.JSON,
+			asst:   `{"name": "Lab"}`,
+			want:   map[string]interface{}{"published": true, "points_possible": 10.0, "peer_reviews": false, "grading_type": "points"},
+		},
+		{
+			name:   "explicit false and zero",
+			format: canvas.JSON,
+			asst:   `{"name": "Lab", "published": false, "points_possible": 0}`,
+			want:   map[string]interface{}{"published": false, "points_possible": 0.0, "peer_reviews": false},
+		},
+		{
+			name:   "explicit null",
+			format: canvas.JSON,
+			asst:   `{"name": "Lab", "due_at": null, "grading_type": null}`,
+			want:   map[string]interface{}{"published": true, "due_at": nil, "grading_type": "", "peer_reviews": false},
+		},
+		{
+			name:   "overrides an explicit default",
+			format: canvas.JSON,
+			asst:   `{"name": "Lab", "peer_reviews": true}`,
+			want:   map[string]interface{}{"peer_reviews": true},
+		},
+		{
+			name:   "yaml",
+			format: canvas.YAML,
+			asst:   "name: Lab\npublished: false\ndue_at: ~\n",
+			want:   map[string]interface{}{"published": false, "due_at": nil, "points_possible": 10.0, "peer_reviews": false},
+		},
+	}
+	for _, test := range tests {
+		asst := decodeAssignment(t, test.asst, test.format)
+		mergeDefault(asst, decodeAssignment(t, def, canvas.JSON))
+
+		// explicit fields are sent even when they are empty
+		raw, err := json.Marshal(asst)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for key, want := range test.want {
+			value, present := got[key]
+			if !present {
+				t.Errorf("%s: %s is missing from %s", test.name, key, raw)
+			} else if !reflect.DeepEqual(value, want) {
+				t.Errorf("%s: %s is %v, expected %v", test.name, key, value, want)
+			}
+		}
+	}
+}
+
+func TestMergeDefaultKeepsDefault(t *testing.T) {
+	def := decodeAssignment(t, `{"published": true, "peer_reviews": false, "allowed_extensions": ["py"]}`, canvas.JSON)
+	first := decodeAssignment(t, `{"name": "One", "published": false, "allowed_extensions": []}`, canvas.JSON)
+	second := decodeAssignment(t, `{"name": "Two"}`, canvas.JSON)
+	mergeDefault(first, def)
+	mergeDefault(second, def)
+
+	if !second.Published || !reflect.DeepEqual(second.AllowedExtensions, []string{"py"}) {
+		t.Errorf("the second assignment got %v, %v from the default", second.Published, second.AllowedExtensions)
+	}
+	if !reflect.DeepEqual(def.Explicit, canvas.Explicit{"peer_reviews": true}) {
+		t.Errorf("the default's explicit fields changed to %v", def.Explicit)
+	}
+	want := canvas.Explicit{"published": true, "allowed_extensions": true, "peer_reviews": true}
+	if !reflect.DeepEqual(first.Explicit, want) {
+		t.Errorf("got explicit fields %v, expected %v", first.Explicit, want)
+	}
+	if len(first.AllowedExtensions) != 0 {
+		t.Errorf("an explicit empty list was filled in with %v", first.AllowedExtensions)
+	}
+}
+
+func TestApplyDefaultsExplicitNull(t *testing.T) {
+	var entries []canvas.AssignmentOrGroup
+	template := `[
+		{"assignment": {"default": true, "due_at": "2024-01-12 23:59:00", "lock_after": "48h", "unlock_before": "168h"}},
+		{"assignment": {"name": "Open", "lock_at": null}},
+		{"assignment": {"name": "Closed"}}
+	]`
+	if err := canvas.Decode([]byte(template), &entries, canvas.JSON); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := applyDefaults(entries, ".", 1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, closed := out[0].Assignment, out[1].Assignment
+	if open.LockAt != nil || open.UnlockAt == nil {
+		t.Errorf("an explicit null lock_at got %v, with unlock_at %v", open.LockAt, open.UnlockAt)
+	}
+	if closed.LockAt == nil || !closed.LockAt.Equal(closed.DueAt.Add(48*time.Hour)) {
+		t.Errorf("lock_at computed from the default is %v", closed.LockAt)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// loadDescription renders the description_file of an assignment into its
+// description. Relative file names are relative to dir, which is normally
+// the directory of the template file.
+func loadDescription(asst *canvas.Assignment, dir string) error {
+	if asst.DescriptionFile == "" {
+		return nil
+	}
+	filename := asst.DescriptionFile
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(dir, filename)
+	}
+	description, err := renderFile(filename)
+	if err != nil {
+		return fmt.Errorf("assignment %q: %v", asst.Name, err)
+	}
+	asst.Description = description
+	return nil
+}
+
+// fileSlug turns a name into a file name, e.g., "Lab 3: Pointers" becomes "lab-3-pointers"
+func fileSlug(name string) string {
+	var out []rune
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && len(out) > 0 {
+				out = append(out, '-')
+			}
+			out = append(out, r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if len(out) == 0 {
+		return "untitled"
+	}
+	return string(out)
+}
+
+// writeDescriptions moves the description of every assignment in a report into
+// a file in dir named for the assignment, and points description_file at it,
+// relative to templateDir, where the report will be written.
+func writeDescriptions(lst []canvas.AssignmentOrGroup, dir, templateDir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, aorg := range lst {
+		asst := aorg.Assignment
+		if asst == nil || asst.Description == "" {
+			continue
+		}
+		slug := fileSlug(asst.Name)
+		filename := filepath.Join(dir, slug+".html")
+		for n := 2; used[filename]; n++ {
+			filename = filepath.Join(dir, fmt.Sprintf("%s-%d.html", slug, n))
+		}
+		used[filename] = true
+		if err := ioutil.WriteFile(filename, []byte(asst.Description), 0644); err != nil {
+			return err
+		}
+		log.Printf("wrote description of %q to %s", asst.Name, filename)
+		asst.Description = ""
+		asst.DescriptionFile = relativePath(templateDir, filename)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// checkDiscussionType makes sure a topic uses a discussion type that Canvas knows
+func checkDiscussionType(topic *canvas.DiscussionTopic) error {
+	switch topic.DiscussionType {
+	case "", canvas.DiscussionSideComment, canvas.DiscussionThreaded:
+		return nil
+	default:
+		return fmt.Errorf("unknown discussion_type %q: expected %s or %s",
+			topic.DiscussionType, canvas.DiscussionThreaded, canvas.DiscussionSideComment)
+	}
+}
+
+// prepareDiscussion checks an ungraded discussion entry.
+func prepareDiscussion(topic *canvas.DiscussionTopic) error {
+	if topic.Title == "" {
+		return fmt.Errorf("discussion entry needs a title")
+	}
+	if err := checkDiscussionType(topic); err != nil {
+		return fmt.Errorf("discussion %q: %v", topic.Title, err)
+	}
+	return nil
+}
+
+// prepareGradedDiscussion checks the discussion topic of a graded discussion
+// assignment. Its submission type is filled in if the assignment leaves it off.
+func prepareGradedDiscussion(asst *canvas.Assignment) error {
+	topic := asst.DiscussionTopic
+	if topic == nil {
+		return nil
+	}
+	if len(asst.SubmissionTypes) == 0 {
+		asst.SubmissionTypes = []string{"discussion_topic"}
+	} else if len(asst.SubmissionTypes) != 1 || asst.SubmissionTypes[0] != "discussion_topic" {
+		return fmt.Errorf("assignment %q has a discussion_topic, so its submission_types must be [discussion_topic]", asst.Name)
+	}
+	if topic.Title != "" || topic.Message != "" || topic.LockAt != nil || topic.Published {
+		return fmt.Errorf("assignment %q: a graded discussion takes its title, message, lock_at, and published from the assignment", asst.Name)
+	}
+	if err := checkDiscussionType(topic); err != nil {
+		return fmt.Errorf("assignment %q: %v", asst.Name, err)
+	}
+	return nil
+}
+
+var fakeDiscussionID = 6000
+
+func uploadDiscussion(ctx context.Context, client *canvas.Client, elt *canvas.DiscussionTopic, courseID int, dry bool) (int, error) {
+	if err := elt.Dump(os.Stdout); err != nil {
+		return 0, err
+	}
+	if dry {
+		if elt.ID == 0 {
+			fakeDiscussionID++
+			return fakeDiscussionID - 1, nil
+		}
+		return elt.ID, nil
+	}
+
+	topic, err := client.SaveDiscussionTopic(ctx, courseID, elt)
+	if err != nil {
+		return 0, fmt.Errorf("uploading discussion %q: %v", elt.Title, err)
+	}
+	return topic.ID, nil
+}
+
+// uploadGradedDiscussion saves the discussion settings of a graded discussion
+// assignment once the assignment has been saved, and records the topic ID.
+func uploadGradedDiscussion(ctx context.Context, client *canvas.Client, elt, asst *canvas.Assignment, courseID int) error {
+	if asst.DiscussionTopic == nil || asst.DiscussionTopic.ID == 0 {
+		return fmt.Errorf("no discussion topic was reported for assignment %q", elt.Name)
+	}
+	if err := client.SaveGradedDiscussion(ctx, courseID, asst.DiscussionTopic.ID, elt.DiscussionTopic); err != nil {
+		return fmt.Errorf("uploading discussion settings for assignment %q: %v", elt.Name, err)
+	}
+	log.Printf("discussion topic %d belongs to assignment %d", asst.DiscussionTopic.ID, asst.ID)
+	elt.DiscussionTopic.ID = asst.DiscussionTopic.ID
+	return nil
+}
+
+// listDiscussions fetches the ungraded discussion topics in a course, ready to be
+// written as template entries. Graded topics are written with their assignments.
+func listDiscussions(ctx context.Context, client *canvas.Client, courseID int) ([]*canvas.DiscussionTopic, error) {
+	topics, err := client.ListDiscussionTopics(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	var lst []*canvas.DiscussionTopic
+	for _, topic := range topics {
+		if topic.AssignmentID != 0 {
+			continue
+		}
+		topic.Cleanup()
+		lst = append(lst, topic)
+	}
+	return lst, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// read reads a template file along with the files it includes, giving one list
+// of entries as if everything were in a single file. Included files are read in
+// the format their names suggest, and the files that their entries refer to
+// are made relative to the directory of the top file.
+func read(filename string, format canvas.Format) ([]canvas.AssignmentOrGroup, error) {
+	return readIncludes(filename, format, nil)
+}
+
+func readIncludes(filename string, format canvas.Format, stack []string) ([]canvas.AssignmentOrGroup, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i, elt := range stack {
+		if elt == abs {
+			cycle := append(append([]string{}, stack[i:]...), abs)
+			return nil, fmt.Errorf("%s: include cycle: %s", filename, strings.Join(cycle, " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	entries, err := readFile(filename, format)
+	if err != nil {
+		return nil, err
+	}
+	var out []canvas.AssignmentOrGroup
+	for _, aorg := range entries {
+		if aorg.Include == "" {
+			out = append(out, aorg)
+			continue
+		}
+		if !isInclude(aorg) {
+			return nil, fmt.Errorf("%s: include entry %q cannot have anything else in it", filename, aorg.Include)
+		}
+		included := includePath(filename, aorg.Include)
+		if _, err := os.Stat(included); err != nil {
+			return nil, fmt.Errorf("%s: include %q: %v", filename, aorg.Include, err)
+		}
+		lst, err := readIncludes(included, canvas.FormatFor(included), stack)
+		if err != nil {
+			return nil, err
+		}
+		rebaseFiles(lst, filepath.Dir(aorg.Include))
+		out = append(out, lst...)
+	}
+	return out, nil
+}
+
+// includePath finds a file named by an include entry in the file from
+func includePath(from, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(from), name)
+}
+
+// isInclude reports whether an include entry is nothing but an include
+func isInclude(aorg canvas.AssignmentOrGroup) bool {
+	bare := canvas.AssignmentOrGroup{Include: aorg.Include, Source: aorg.Source}
+	return aorg == bare
+}
+
+// rebaseFiles makes the relative file names in included entries relative to
+// the including file, given the directory of the included file relative to it.
+func rebaseFiles(entries []canvas.AssignmentOrGroup, dir string) {
+	if dir == "." {
+		return
+	}
+	rebase := func(name *string) {
+		if *name != "" && !filepath.IsAbs(*name) {
+			*name = filepath.Join(dir, *name)
+		}
+	}
+	rebaseAssignment := func(asst *canvas.Assignment) {
+		if asst != nil {
+			rebase(&asst.DescriptionFile)
+		}
+	}
+	for _, aorg := range entries {
+		rebaseAssignment(aorg.Assignment)
+		if aorg.Group != nil {
+			rebaseAssignment(aorg.Group.Default)
+		}
+		if aorg.Series != nil {
+			rebaseAssignment(aorg.Series.Assignment)
+			for _, item := range aorg.Series.Items {
+				rebaseAssignment(item)
+			}
+		}
+		if aorg.Quiz != nil {
+			rebase(&aorg.Quiz.QuestionsFile)
+		}
+		if aorg.Page != nil {
+			rebase(&aorg.Page.BodyFile)
+		}
+	}
+}
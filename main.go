@@ -1,141 +1,466 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
-	"fmt"
+	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-)
-
-const apiEndpoint = "https://dixie.instructure.com"
+	"path/filepath"
+	"reflect"
+	"time"
 
-var authHeader string
+	"github.com/russross/canvasassignments/canvas"
+)
 
 func main() {
-	token := os.Getenv("CANVAS_TOKEN")
-	if token == "" {
-		log.Fatalf("Must set CANVAS_TOKEN environment variable")
-	}
-	authHeader = fmt.Sprintf("Bearer %s", token)
-
 	var (
-		courseID           int
-		assignmentID       int
-		assignmentGroupID  int
-		includeAssignments bool
-		file               string
-		dry                bool
+		profileName       string
+		courseID          int
+		assignmentID      int
+		assignmentGroupID int
+		file              string
+		formatName        string
+		shiftFrom         string
+		shiftTo           string
+		clearIDs          bool
+		validate          bool
+		opts              fileOptions
+		report            reportOptions
+		perPage           int
+		retries           int
+		retryWait         time.Duration
 	)
-	flag.IntVar(&courseID, "course", 0, "Course ID")
+	flag.StringVar(&profileName, "profile", "", "Config file profile to use (default $CANVAS_PROFILE)")
+	flag.IntVar(&courseID, "course", 0, "Course ID (default from profile)")
 	flag.IntVar(&assignmentID, "assignment", 0, "Assignment ID")
 	flag.IntVar(&assignmentGroupID, "assignment_group", 0, "Assignment Group ID")
-	flag.BoolVar(&includeAssignments, "include_assignments", false, "Fetch assignments in group")
+	flag.BoolVar(&report.assignments, "include_assignments", false, "Fetch assignments in group")
+	flag.BoolVar(&report.quizzes, "include_quizzes", false, "With -include_assignments, write quizzes and their questions in place of their assignments")
+	flag.BoolVar(&report.discussions, "include_discussions", false, "Fetch the course's ungraded discussion topics along with its groups (graded ones come with their assignments)")
+	flag.BoolVar(&report.modules, "include_modules", false, "Fetch the course's modules and their items along with its groups")
+	flag.StringVar(&report.pagesDir, "pages", "", "Fetch the course's pages along with its groups, writing each page body to a file in this directory")
+	flag.StringVar(&report.descriptionsDir, "descriptions", "", "When fetching assignments, write each description to a file in this directory and refer to it with description_file")
+	flag.StringVar(&report.output, "output", "", "Write the report to this file instead of standard output (files written with -pages and -descriptions are named relative to it)")
 	flag.StringVar(&file, "file", "", "Upload courses and groups from this file")
-	flag.BoolVar(&dry, "dry", false, "Dry run")
+	flag.StringVar(&formatName, "format", "", "File and report format: json or yaml (default from the file extension, or json)")
+	flag.BoolVar(&opts.dry, "dry", false, "Dry run")
+	flag.StringVar(&opts.calendar, "calendar", "", "Holiday calendar file to apply to dates in -file")
+	flag.StringVar(&opts.rubrics, "rubrics", "", "Rubric library file for assignments in -file to refer to with rubric_ref")
+	flag.BoolVar(&opts.plan, "plan", false, "Compare the file to the course and report what upload would change (exit status 2 if changes are pending)")
+	flag.BoolVar(&opts.prune, "prune", false, "Delete assignments and groups in the course that are not in the file")
+	flag.BoolVar(&opts.force, "force", false, "With -prune, delete assignments even if they have submissions")
+	flag.BoolVar(&opts.yes, "yes", false, "With -prune, delete without asking for confirmation")
+	flag.BoolVar(&opts.writeIDs, "write_ids", false, "After upload, record the IDs of newly created groups, assignments, and modules in the file")
+	flag.IntVar(&opts.moveTo, "move_assignments_to", 0, "With -prune, move kept assignments from deleted groups to this group (default first group in file)")
+	flag.StringVar(&shiftFrom, "shift_from", "", "Start date (YYYY-MM-DD) of the term the file was written for; use with -shift_to")
+	flag.StringVar(&shiftTo, "shift_to", "", "Start date (YYYY-MM-DD) of the new term; dates in -file are moved to the same week and weekday and printed")
+	flag.BoolVar(&validate, "validate", false, "Check -file (and the files it includes) for errors without contacting Canvas")
+	flag.BoolVar(&clearIDs, "clear_ids", false, "With -shift_to, remove course-specific IDs so the result can be uploaded to a new course")
+	flag.IntVar(&perPage, "per_page", 100, "Number of results to request per page")
+	flag.IntVar(&retries, "retries", 5, "Maximum retries for a failed request (0 to disable)")
+	flag.DurationVar(&retryWait, "retry_wait", time.Second, "Delay before the first retry (doubles with each attempt)")
 	flag.Parse()
 
+	format := canvas.JSON
+	if file != "" {
+		format = canvas.FormatFor(file)
+	} else if report.output != "" {
+		format = canvas.FormatFor(report.output)
+	}
+	if formatName != "" {
+		var err error
+		if format, err = canvas.ParseFormat(formatName); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	opts.format = format
+	report.format = format
+
+	// offline commands that do not need a Canvas instance
+	if file != "" && (shiftFrom != "" || shiftTo != "") {
+		if err := shiftFile(os.Stdout, file, format, shiftFrom, shiftTo, clearIDs); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	if file != "" && validate {
+		if err := checkFile(file, courseID, opts); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	profile := loadProfile(profileName)
+	client := canvas.NewClient(profile.URL, profile.Token)
+	client.PerPage = perPage
+	client.MaxRetries = retries
+	client.RetryWait = retryWait
+	client.Logf = log.Printf
+
+	// the profile's course stands in only when a command needs a course and
+	// neither -course nor the template file names one
+	needCourse := assignmentID > 0 || assignmentGroupID > 0 || report.requested()
+	if file != "" {
+		needCourse = templateCourse(file, format) == 0
+	}
+	if courseID == 0 && needCourse {
+		courseID = profile.Course
+	}
+	ctx := context.Background()
+
+	var err error
 	switch {
 	case courseID > 0 && assignmentID > 0 && file == "":
-		reportAssignment(courseID, assignmentID)
+		err = reportAssignment(ctx, client, courseID, assignmentID, report)
 
 	case courseID > 0 && assignmentGroupID > 0 && file == "":
-		reportAssignmentGroup(courseID, assignmentGroupID, includeAssignments)
+		err = reportAssignmentGroup(ctx, client, courseID, assignmentGroupID, report)
 
 	case courseID > 0 && file == "":
-		reportAllAssignmentGroups(courseID, includeAssignments)
+		err = reportAllAssignmentGroups(ctx, client, courseID, report)
 
 	case file != "":
-		templates := read(file)
-		entries, courseID := applyDefaults(templates, courseID)
-		upload(entries, courseID, dry)
+		err = processFile(ctx, client, file, courseID, opts)
 
 	default:
 		flag.Usage()
 	}
+	if err == errChangesPending {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// errChangesPending is returned by processFile when a plan finds changes to make
+var errChangesPending = errors.New("changes are pending")
+
+// fileOptions control what processFile does with a template file
+type fileOptions struct {
+	format   canvas.Format
+	calendar string
+	rubrics  string
+	dry      bool
+	plan     bool
+	prune    bool
+	force    bool
+	yes      bool
+	moveTo   int
+	writeIDs bool
+}
+
+// loadFile validates a template file along with its calendar and rubric
+// library files, then reads and expands it, ready to upload.
+func loadFile(file string, courseID int, opts fileOptions) ([]canvas.AssignmentOrGroup, int, error) {
+	if err := validateTemplate(file, opts.format); err != nil {
+		return nil, 0, err
+	}
+	if opts.calendar != "" {
+		if err := validateFile(opts.calendar, canvas.FormatFor(opts.calendar), reflect.TypeOf(canvas.Calendar{}), "calendar"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if opts.rubrics != "" {
+		if err := validateTemplate(opts.rubrics, canvas.FormatFor(opts.rubrics)); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	templates, err := read(file, opts.format)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = loadQuestionFiles(templates, filepath.Dir(file)); err != nil {
+		return nil, 0, err
+	}
+	if err = loadPageFiles(templates, filepath.Dir(file)); err != nil {
+		return nil, 0, err
+	}
+	var cal *canvas.Calendar
+	if opts.calendar != "" {
+		if cal, err = readCalendar(opts.calendar, canvas.FormatFor(opts.calendar)); err != nil {
+			return nil, 0, err
+		}
+	}
+	var rubrics []*canvas.LibraryRubric
+	if opts.rubrics != "" {
+		if rubrics, err = readRubrics(opts.rubrics, canvas.FormatFor(opts.rubrics)); err != nil {
+			return nil, 0, err
+		}
+	}
+	return applyDefaults(templates, filepath.Dir(file), courseID, cal, rubrics)
+}
+
+// checkFile does everything short of contacting Canvas to make sure a
+// template file is ready to upload.
+func checkFile(file string, courseID int, opts fileOptions) error {
+	if courseID == 0 {
+		courseID = templateCourse(file, opts.format)
+	}
+	if courseID == 0 {
+		// no course is needed to check a file, so stand in for one the file does not name
+		courseID = -1
+	}
+	entries, _, err := loadFile(file, courseID, opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s is valid (%d entries)", file, len(entries))
+	return nil
+}
+
+// templateCourse returns the first course ID named in a template file, or 0
+// if it names none (or cannot be read).
+func templateCourse(file string, format canvas.Format) int {
+	templates, err := read(file, format)
+	if err != nil {
+		return 0
+	}
+	for _, aorg := range templates {
+		if aorg.Assignment != nil && aorg.Assignment.CourseID != 0 {
+			return aorg.Assignment.CourseID
+		}
+	}
+	return 0
+}
+
+func processFile(ctx context.Context, client *canvas.Client, file string, courseID int, opts fileOptions) error {
+	entries, courseID, err := loadFile(file, courseID, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.plan {
+		steps, err := plan(ctx, client, entries, courseID)
+		if err != nil {
+			return err
+		}
+		if printPlan(os.Stdout, steps, opts.prune) {
+			return errChangesPending
+		}
+		return nil
+	}
+
+	// find prunable objects before upload assigns IDs to new ones, and
+	// confirm the deletions before anything in the course changes
+	var pruning *pruneList
+	if opts.prune {
+		steps, err := plan(ctx, client, entries, courseID)
+		if err != nil {
+			return err
+		}
+		pruning = findPrunable(steps, opts.force)
+		if opts.dry || pruning.Empty() {
+			pruning.Print(os.Stdout)
+		} else if !opts.yes {
+			ok, err := pruning.Confirm(os.Stdin, os.Stdout)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				log.Printf("prune cancelled, so nothing was uploaded")
+				return nil
+			}
+		}
+	}
+
+	err = upload(ctx, client, entries, courseID, opts.dry)
+
+	// record new IDs even if upload stopped part way through
+	if opts.writeIDs && !opts.dry {
+		changed, writeErr := writeIDs(file, opts.format, entries)
+		if writeErr != nil {
+			log.Printf("unable to write new IDs to %s: %v", file, writeErr)
+		} else if changed > 0 {
+			log.Printf("recorded %d new IDs in %s", changed, file)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if pruning == nil || opts.dry || pruning.Empty() {
+		return nil
+	}
+	moveTo := opts.moveTo
+	if moveTo == 0 {
+		moveTo = firstGroupID(entries)
+	}
+	return prune(ctx, client, courseID, pruning, moveTo)
+}
+
+// reportOptions control what the report commands fetch and how they write it
+type reportOptions struct {
+	format          canvas.Format
+	output          string
+	assignments     bool
+	quizzes         bool
+	discussions     bool
+	modules         bool
+	pagesDir        string
+	descriptionsDir string
 }
 
-func reportAssignment(courseID, assignmentID int) {
+// requested reports whether any report option was given
+func (opts reportOptions) requested() bool {
+	return opts.output != "" || opts.assignments || opts.quizzes || opts.discussions ||
+		opts.modules || opts.pagesDir != "" || opts.descriptionsDir != ""
+}
+
+func reportAssignment(ctx context.Context, client *canvas.Client, courseID, assignmentID int, opts reportOptions) error {
 	// fetch the assignment
-	targetURL := fmt.Sprintf("%s/api/v1/courses/%d/assignments/%d", apiEndpoint, courseID, assignmentID)
-	asst := new(Assignment)
-	mustFetch(targetURL, asst)
+	asst, err := client.GetAssignment(ctx, courseID, assignmentID)
+	if err != nil {
+		return err
+	}
 
-	// output it as JSON
+	// output it as JSON or YAML
 	asst.Cleanup()
-	asst.Dump()
+	lst := []canvas.AssignmentOrGroup{{Assignment: asst}}
+	if opts.descriptionsDir != "" {
+		if err = writeDescriptions(lst, opts.descriptionsDir, opts.templateDir()); err != nil {
+			return err
+		}
+	}
+	return writeReport(lst, opts)
 }
 
-func reportAssignmentGroup(courseID, assignmentGroupID int, includeAssignments bool) {
+func reportAssignmentGroup(ctx context.Context, client *canvas.Client, courseID, assignmentGroupID int, opts reportOptions) error {
 	// fetch the assignment group
-	include := ""
-	if includeAssignments {
-		include = "?include=assignments"
+	group, err := client.GetAssignmentGroup(ctx, courseID, assignmentGroupID)
+	if err != nil {
+		return err
 	}
-	targetURL := fmt.Sprintf("%s/api/v1/courses/%d/assignment_groups/%d%s", apiEndpoint, courseID, assignmentGroupID, include)
-	group := new(AssignmentGroup)
-	mustFetch(targetURL, group)
 
-	dumpGroups([]*AssignmentGroup{group}, includeAssignments)
+	// fetch its assignments separately so they can be paged
+	if opts.assignments {
+		if group.Assignments, err = client.ListGroupAssignments(ctx, courseID, assignmentGroupID); err != nil {
+			return err
+		}
+	}
+
+	// fetch quizzes to write in place of their assignments
+	groups := []*canvas.AssignmentGroup{group}
+	var quizzes map[int]*canvas.Quiz
+	if opts.quizzes {
+		if quizzes, err = fetchGroupQuizzes(ctx, client, courseID, groups); err != nil {
+			return err
+		}
+	}
+
+	return dumpGroups(groups, quizzes, nil, nil, nil, opts)
 }
 
-func reportAllAssignmentGroups(courseID int, includeAssignments bool) {
-	// fetch the assignment group
-	include := ""
-	if includeAssignments {
-		include = "?include=assignments"
+func reportAllAssignmentGroups(ctx context.Context, client *canvas.Client, courseID int, opts reportOptions) error {
+	// fetch the assignment groups
+	groups, err := client.ListAssignmentGroups(ctx, courseID, opts.assignments)
+	if err != nil {
+		return err
+	}
+
+	// fetch quizzes to write in place of their assignments
+	var quizzes map[int]*canvas.Quiz
+	if opts.quizzes {
+		if quizzes, err = fetchGroupQuizzes(ctx, client, courseID, groups); err != nil {
+			return err
+		}
+	}
+
+	// fetch the ungraded discussions
+	var discussions []*canvas.DiscussionTopic
+	if opts.discussions {
+		if discussions, err = listDiscussions(ctx, client, courseID); err != nil {
+			return err
+		}
+	}
+
+	// fetch the pages, writing their bodies to files
+	var pages []*canvas.Page
+	if opts.pagesDir != "" {
+		if pages, err = exportPages(ctx, client, courseID, opts.pagesDir, opts.templateDir()); err != nil {
+			return err
+		}
+	}
+
+	// fetch the modules
+	var modules []*canvas.Module
+	if opts.modules {
+		if modules, err = listModules(ctx, client, courseID); err != nil {
+			return err
+		}
 	}
-	targetURL := fmt.Sprintf("%s/api/v1/courses/%d/assignment_groups%s", apiEndpoint, courseID, include)
-	var groups []*AssignmentGroup
-	mustFetch(targetURL, &groups)
 
-	dumpGroups(groups, includeAssignments)
+	return dumpGroups(groups, quizzes, discussions, pages, modules, opts)
 }
 
-func dumpGroups(groups []*AssignmentGroup, includeAssignments bool) {
+func dumpGroups(groups []*canvas.AssignmentGroup, quizzes map[int]*canvas.Quiz, discussions []*canvas.DiscussionTopic, pages []*canvas.Page, modules []*canvas.Module, opts reportOptions) error {
 	// create a single list
-	var lst []AssignmentOrGroup
+	var lst []canvas.AssignmentOrGroup
 	for _, group := range groups {
 		group.Cleanup()
 		assts := group.Assignments
 		group.Assignments = nil
-		lst = append(lst, AssignmentOrGroup{Group: group})
+		lst = append(lst, canvas.AssignmentOrGroup{Group: group})
 		for _, elt := range assts {
-			lst = append(lst, AssignmentOrGroup{Assignment: elt})
+			if quiz, present := quizzes[elt.QuizID]; present && elt.QuizID != 0 {
+				lst = append(lst, canvas.AssignmentOrGroup{Quiz: quiz})
+				continue
+			}
+			lst = append(lst, canvas.AssignmentOrGroup{Assignment: elt})
 		}
 	}
-	Dump(lst)
-}
 
-func mustFetch(targetURL string, elt interface{}) {
-	token := os.Getenv("CANVAS_TOKEN")
-	if token == "" {
-		log.Fatalf("Must set CANVAS_TOKEN environment variable")
+	for _, topic := range discussions {
+		lst = append(lst, canvas.AssignmentOrGroup{Discussion: topic})
 	}
-
-	// fetch the object
-	req, err := http.NewRequest("GET", targetURL, nil)
-	if err != nil {
-		log.Fatalf("Error creating HTTP request: %v", err)
+	for _, page := range pages {
+		lst = append(lst, canvas.AssignmentOrGroup{Page: page})
 	}
-	req.Header.Add("Authorization", authHeader)
 
-	// report the equivalent curl command
-	//log.Printf(`curl -H "Authorization: Bearer $CANVAS_TOKEN" '%s'`, targetURL)
+	// modules come after the assignments, discussions, and pages they list
+	for _, module := range modules {
+		lst = append(lst, canvas.AssignmentOrGroup{Module: module})
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	// rubrics shared by several assignments become library entries
+	lst, err := collapseRubrics(lst)
 	if err != nil {
-		log.Fatalf("GET error: %v", err)
+		return err
+	}
+
+	if opts.descriptionsDir != "" {
+		if err = writeDescriptions(lst, opts.descriptionsDir, opts.templateDir()); err != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Fatalf("GET response %d: %s", resp.StatusCode, resp.Status)
+	return writeReport(lst, opts)
+}
+
+// templateDir is the directory that the report is written to. Files that the
+// report refers to are named relative to it.
+func (opts reportOptions) templateDir() string {
+	if opts.output == "" {
+		return "."
 	}
+	return filepath.Dir(opts.output)
+}
 
-	// decode it
-	decoder := json.NewDecoder(resp.Body)
-	if err = decoder.Decode(elt); err != nil {
-		log.Fatalf("Error decoding object: %v", err)
+// writeReport writes the report entries to the output file, or to standard output.
+func writeReport(lst []canvas.AssignmentOrGroup, opts reportOptions) error {
+	if opts.output == "" {
+		return canvas.Encode(os.Stdout, lst, opts.format)
+	}
+	var buf bytes.Buffer
+	if err := canvas.Encode(&buf, lst, opts.format); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(opts.output, buf.Bytes(), 0644); err != nil {
+		return err
 	}
+	log.Printf("wrote %s", opts.output)
+	return nil
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestTemplateCourse(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     int
+	}{
+		{"none", `[{"assignment_group": {"name": "Labs"}}, {"assignment": {"name": "Lab 1"}}]`, 0},
+		{"assignment", `[{"assignment_group": {"name": "Labs"}}, {"assignment": {"name": "Lab 1", "course_id": 12}}]`, 12},
+		{"default", `[{"assignment": {"default": true, "course_id": 34}}, {"assignment": {"name": "Lab 1"}}]`, 34},
+		{"unreadable", `[{"assignment": `, 0},
+	}
+	for _, test := range tests {
+		dir := tempDir(t, map[string]string{"course.json": test.contents})
+		if got := templateCourse(filepath.Join(dir, "course.json"), canvas.JSON); got != test.want {
+			t.Errorf("%s: got course %d, expected %d", test.name, got, test.want)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReportRequested(t *testing.T) {
+	if (reportOptions{format: canvas.YAML}).requested() {
+		t.Errorf("a format alone counts as a report request")
+	}
+	for _, opts := range []reportOptions{{output: "course.json"}, {assignments: true}, {modules: true}, {pagesDir: "pages"}} {
+		if !opts.requested() {
+			t.Errorf("%+v does not count as a report request", opts)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/russross/blackfriday/v2"
+)
+
+// codeStyle is the color scheme for highlighted code blocks. Canvas strips
+// style sheets, so the colors are written inline.
+const codeStyle = "github"
+
+// renderFile reads a Markdown or HTML file and returns it as HTML.
+// The format is chosen by the file extension.
+func renderFile(filename string) (string, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return renderMarkdown(string(contents)), nil
+	case ".html", ".htm":
+		return string(contents), nil
+	default:
+		return "", fmt.Errorf("%s: expected a .md, .markdown, .html, or .htm file", filename)
+	}
+}
+
+// renderMarkdown converts Markdown to HTML that Canvas will keep. Tables are
+// supported, fenced code blocks that name a language are highlighted, and
+// TeX math between $ or $$ becomes a Canvas equation image.
+func renderMarkdown(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text, math := extractMath(text)
+	renderer := &highlightRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags: blackfriday.CommonHTMLFlags,
+		}),
+	}
+	out := string(blackfriday.Run([]byte(text),
+		blackfriday.WithRenderer(renderer),
+		blackfriday.WithExtensions(blackfriday.CommonExtensions)))
+
+	for i, elt := range math {
+		placeholder := mathPlaceholder(i)
+		img := equationImage(elt.tex)
+		if elt.display {
+			out = strings.Replace(out, "<p>"+placeholder+"</p>", `<p style="text-align: center;">`+img+"</p>", 1)
+		}
+		out = strings.Replace(out, placeholder, img, 1)
+	}
+	return out
+}
+
+// highlightRenderer is the standard HTML renderer with highlighted code blocks
+type highlightRenderer struct {
+	*blackfriday.HTMLRenderer
+}
+
+func (r *highlightRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if node.Type == blackfriday.CodeBlock {
+		fields := strings.Fields(string(node.Info))
+		if len(fields) > 0 && highlight(w, string(node.Literal), fields[0]) == nil {
+			return blackfriday.GoToNext
+		}
+	}
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+// highlight writes code in the given language as HTML with inline colors.
+// It fails without writing anything if the language is unknown.
+func highlight(w io.Writer, code, language string) error {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return fmt.Errorf("unknown language %q", language)
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	formatter := chromahtml.New(chromahtml.WithClasses(false))
+	if err = formatter.Format(&buf, styles.Get(codeStyle), iterator); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+type mathSpan struct {
+	tex     string
+	display bool
+}
+
+// mathPlaceholder stands in for a math span while the Markdown is rendered.
+// It is made of letters and digits so Markdown leaves it alone.
+func mathPlaceholder(i int) string {
+	return fmt.Sprintf("zzmath%dzz", i)
+}
+
+var fenceLine = regexp.MustCompile("^ {0,3}(```|~~~)")
+
+// extractMath replaces the math in Markdown text with placeholders so that
+// Markdown does not treat TeX punctuation as formatting. Math is $$...$$ for
+// a display equation (which may span lines) or $...$ inline, where the inline
+// form must not start or end with a space, so prices like $5 and $10 are left
+// alone. \$ is a literal dollar sign. Code spans and code blocks are skipped.
+func extractMath(text string) (string, []mathSpan) {
+	var math []mathSpan
+	var out strings.Builder
+	inFence, inIndented, prevBlank := false, false, true
+	for pos := 0; pos < len(text); {
+		// each pass handles one line, or more if a display equation runs onto later lines
+		end := lineEnd(text, pos)
+		line := strings.TrimRight(text[pos:end], "\n")
+		blank := strings.TrimSpace(line) == ""
+
+		// code blocks are copied as they are
+		fence := fenceLine.MatchString(line)
+		if fence {
+			inFence = !inFence
+		}
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		inIndented = (indented && (prevBlank || inIndented)) || (inIndented && blank)
+		prevBlank = blank
+		if fence || inFence || inIndented {
+			out.WriteString(text[pos:end])
+			pos = end
+			continue
+		}
+
+		i := pos
+		for i < end {
+			switch {
+			case strings.HasPrefix(text[i:], "\\$"):
+				out.WriteByte('$')
+				i += 2
+
+			case text[i] == '`':
+				// copy a code span through its closing run of backticks
+				run := 1
+				for i+run < end && text[i+run] == '`' {
+					run++
+				}
+				close := strings.Index(text[i+run:end], text[i:i+run])
+				if close < 0 {
+					out.WriteString(text[i : i+run])
+					i += run
+					continue
+				}
+				out.WriteString(text[i : i+run+close+run])
+				i += run + close + run
+
+			case strings.HasPrefix(text[i:], "$$"):
+				close := strings.Index(text[i+2:], "$$")
+				if close < 0 {
+					out.WriteString("$$")
+					i += 2
+					continue
+				}
+				out.WriteString(mathPlaceholder(len(math)))
+				math = append(math, mathSpan{tex: strings.TrimSpace(text[i+2 : i+2+close]), display: true})
+				i += 2 + close + 2
+				if i > end {
+					end = lineEnd(text, i)
+					prevBlank = false
+				}
+
+			case text[i] == '$':
+				close := strings.IndexByte(text[i+1:end], '$')
+				after := i + 1 + close + 1
+				if close <= 0 || text[i+1] == ' ' || text[i+close] == ' ' ||
+					(after < end && text[after] >= '0' && text[after] <= '9') {
+					out.WriteByte('$')
+					i++
+					continue
+				}
+				out.WriteString(mathPlaceholder(len(math)))
+				math = append(math, mathSpan{tex: text[i+1 : i+1+close]})
+				i = after
+
+			default:
+				out.WriteByte(text[i])
+				i++
+			}
+		}
+		pos = end
+	}
+	return out.String(), math
+}
+
+// lineEnd returns the index just past the newline that ends the line containing pos
+func lineEnd(text string, pos int) int {
+	if n := strings.IndexByte(text[pos:], '\n'); n >= 0 {
+		return pos + n + 1
+	}
+	return len(text)
+}
+
+// equationImage returns the image tag that the Canvas editor uses for an equation.
+// Canvas expects the TeX in the image path to be escaped twice.
+func equationImage(tex string) string {
+	escaped := html.EscapeString(tex)
+	return fmt.Sprintf(`<img class="equation_image" title="%s" src="/equation_images/%s?scale=1" alt="LaTeX: %s" data-equation-content="%s" />`,
+		escaped, encodeURIComponent(encodeURIComponent(tex)), escaped, escaped)
+}
+
+// encodeURIComponent escapes a string the way the JavaScript function of the same name does
+func encodeURIComponent(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// checkModule makes sure a module entry is complete. Prerequisites must name
+// modules that appear earlier in the file, which is also what Canvas requires.
+func checkModule(module *canvas.Module, earlier map[string]bool) error {
+	if module.Name == "" {
+		return fmt.Errorf("module entry needs a name")
+	}
+	for _, name := range module.Prerequisites {
+		if !earlier[name] {
+			return fmt.Errorf("module %q: prerequisite %q is not a module earlier in the file", module.Name, name)
+		}
+	}
+	for _, item := range module.Items {
+		switch item.Type {
+		case canvas.ItemAssignment, canvas.ItemQuiz, canvas.ItemDiscussion:
+			if item.ContentID == 0 && item.Title == "" {
+				return fmt.Errorf("module %q: %s items need a title or a content_id", module.Name, item.Type)
+			}
+		case canvas.ItemFile, canvas.ItemTool:
+			if item.ContentID == 0 {
+				return fmt.Errorf("module %q: %s item %q needs a content_id", module.Name, item.Type, item.Title)
+			}
+		case canvas.ItemPage:
+			if item.PageURL == "" {
+				return fmt.Errorf("module %q: page item %q needs a page_url", module.Name, item.Title)
+			}
+		case canvas.ItemExternal:
+			if item.ExternalURL == "" {
+				return fmt.Errorf("module %q: external URL item %q needs an external_url", module.Name, item.Title)
+			}
+		case canvas.ItemSubHeader:
+			if item.Title == "" {
+				return fmt.Errorf("module %q: sub-header items need a title", module.Name)
+			}
+		default:
+			return fmt.Errorf("module %q: unknown item type %q", module.Name, item.Type)
+		}
+		if req := item.CompletionRequirement; req != nil {
+			switch req.Type {
+			case canvas.RequireView, canvas.RequireSubmit, canvas.RequireContribute, canvas.RequireMarkDone:
+			case canvas.RequireMinScore:
+				if req.MinScore <= 0 {
+					return fmt.Errorf("module %q: item %q needs a min_score for its completion requirement", module.Name, item.Title)
+				}
+			default:
+				return fmt.Errorf("module %q: item %q has unknown completion requirement %q", module.Name, item.Title, req.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// contentNames maps the names of uploaded assignments, quizzes, and discussions to IDs for
+// resolving module items. Names that are used more than once for the same kind
+// of item map to 0 since they are ambiguous.
+type contentNames map[string]int
+
+func (names contentNames) add(itemType, name string, id int) {
+	key := itemType + ":" + name
+	if _, present := names[key]; present {
+		names[key] = 0
+		return
+	}
+	names[key] = id
+}
+
+func (names contentNames) lookup(itemType, name string) (int, bool) {
+	id, present := names[itemType+":"+name]
+	return id, present
+}
+
+var fakeModuleID = 4000
+
+// uploadModules saves the modules in a list of entries, in file order, after the
+// assignments, quizzes, and discussions they refer to have been saved. Modules without IDs are matched by
+// name against the course so that re-running does not create copies.
+func uploadModules(ctx context.Context, client *canvas.Client, all []canvas.AssignmentOrGroup, courseID int, names contentNames, dry bool) error {
+	var existing []*canvas.Module
+	moduleIDs := make(map[string]int)
+	position := 0
+	for _, aorg := range all {
+		elt := aorg.Module
+		if elt == nil {
+			continue
+		}
+		position++
+		if elt.Position == 0 {
+			elt.Position = position
+		}
+
+		// find the module this replaces
+		if elt.ID == 0 && !dry {
+			if existing == nil {
+				modules, err := client.ListModules(ctx, courseID)
+				if err != nil {
+					return fmt.Errorf("listing modules: %v", err)
+				}
+				existing = append([]*canvas.Module{}, modules...)
+			}
+			for _, module := range existing {
+				if module.Name == elt.Name {
+					elt.ID = module.ID
+					break
+				}
+			}
+		}
+
+		// fill in IDs from names, replacing any IDs that were given
+		if elt.Prerequisites != nil {
+			elt.PrerequisiteModuleIDs = []int{}
+			for _, name := range elt.Prerequisites {
+				elt.PrerequisiteModuleIDs = append(elt.PrerequisiteModuleIDs, moduleIDs[name])
+			}
+		}
+		for _, item := range elt.Items {
+			if (item.Type != canvas.ItemAssignment && item.Type != canvas.ItemQuiz && item.Type != canvas.ItemDiscussion) || item.ContentID != 0 {
+				continue
+			}
+			kind := strings.ToLower(item.Type)
+			id, present := names.lookup(item.Type, item.Title)
+			if !present {
+				return fmt.Errorf("module %q: no %s named %q in the file", elt.Name, kind, item.Title)
+			}
+			if id == 0 {
+				return fmt.Errorf("module %q: more than one %s is named %q; use content_id", elt.Name, kind, item.Title)
+			}
+			item.ContentID = id
+		}
+
+		id, err := uploadModule(ctx, client, elt, courseID, dry)
+		if err != nil {
+			return err
+		}
+		if elt.ID == 0 {
+			log.Printf("new module ID %d", id)
+			if !dry {
+				elt.ID = id
+			}
+		}
+		moduleIDs[elt.Name] = id
+	}
+	return nil
+}
+
+func uploadModule(ctx context.Context, client *canvas.Client, elt *canvas.Module, courseID int, dry bool) (int, error) {
+	log.Printf("uploading module %d (%s)", elt.ID, elt.Name)
+	if err := elt.Dump(os.Stdout); err != nil {
+		return 0, err
+	}
+	if dry {
+		if elt.ID == 0 {
+			fakeModuleID++
+			return fakeModuleID - 1, nil
+		}
+		return elt.ID, nil
+	}
+
+	module, err := client.SaveModule(ctx, courseID, elt)
+	if err != nil {
+		return 0, fmt.Errorf("uploading module %q: %v", elt.Name, err)
+	}
+
+	// an items list (even an empty one) replaces the existing items
+	if elt.Items != nil {
+		if err = client.SyncModuleItems(ctx, courseID, module.ID, elt.Items); err != nil {
+			return module.ID, fmt.Errorf("uploading items for module %q: %v", elt.Name, err)
+		}
+	}
+	return module.ID, nil
+}
+
+// listModules fetches the modules in a course with their items, ready to
+// be written as template entries. Prerequisites are given by name.
+func listModules(ctx context.Context, client *canvas.Client, courseID int) ([]*canvas.Module, error) {
+	modules, err := client.ListModules(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string)
+	for _, module := range modules {
+		names[module.ID] = module.Name
+	}
+	for _, module := range modules {
+		if module.Items, err = client.ListModuleItems(ctx, courseID, module.ID); err != nil {
+			return nil, err
+		}
+		module.Cleanup()
+		module.Position = 0
+		for _, id := range module.PrerequisiteModuleIDs {
+			module.Prerequisites = append(module.Prerequisites, names[id])
+		}
+		module.PrerequisiteModuleIDs = nil
+	}
+	return modules, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// loadPageFiles reads the body_file of every page entry into its body. Relative
+// file names are relative to dir, which is normally the directory of the template file.
+func loadPageFiles(entries []canvas.AssignmentOrGroup, dir string) error {
+	for _, aorg := range entries {
+		page := aorg.Page
+		if page == nil || page.BodyFile == "" {
+			continue
+		}
+		if page.Body != "" {
+			return fmt.Errorf("page %q has both a body and a body_file", page.Title)
+		}
+		filename := page.BodyFile
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		body, err := renderFile(filename)
+		if err != nil {
+			return fmt.Errorf("page %q: %v", page.Title, err)
+		}
+		page.Body = body
+	}
+	return nil
+}
+
+// checkPage makes sure a page entry is complete and that Canvas will accept it.
+func checkPage(page *canvas.Page) error {
+	if page.Title == "" {
+		return fmt.Errorf("page entry needs a title")
+	}
+	if page.FrontPage && !page.Published {
+		return fmt.Errorf("page %q: the front page must be published", page.Title)
+	}
+	if page.EditingRoles != "" {
+		for _, role := range strings.Split(page.EditingRoles, ",") {
+			switch strings.TrimSpace(role) {
+			case canvas.EditTeachers, canvas.EditStudents, canvas.EditMembers, canvas.EditPublic:
+			default:
+				return fmt.Errorf("page %q: unknown editing role %q: expected a list of %s, %s, %s, or %s",
+					page.Title, role, canvas.EditTeachers, canvas.EditStudents, canvas.EditMembers, canvas.EditPublic)
+			}
+		}
+	}
+	return nil
+}
+
+// findPage finds the live page that a page entry refers to, by slug if it
+// has one and otherwise by title. It returns nil for a new page.
+func findPage(elt *canvas.Page, pages []*canvas.Page) *canvas.Page {
+	for _, page := range pages {
+		if elt.URL != "" && page.URL == elt.URL {
+			return page
+		}
+		if elt.URL == "" && page.Title == elt.Title {
+			return page
+		}
+	}
+	return nil
+}
+
+// uploadPage creates or updates a page and returns its slug.
+func uploadPage(ctx context.Context, client *canvas.Client, elt *canvas.Page, courseID int, pages []*canvas.Page, dry bool) (string, error) {
+	if err := elt.Dump(os.Stdout); err != nil {
+		return "", err
+	}
+	slug := ""
+	if match := findPage(elt, pages); match != nil {
+		slug = match.URL
+	}
+	if dry {
+		return slug, nil
+	}
+
+	page, err := client.SavePage(ctx, courseID, slug, elt)
+	if err != nil {
+		return "", fmt.Errorf("uploading page %q: %v", elt.Title, err)
+	}
+	return page.URL, nil
+}
+
+// exportPages fetches the pages in a course, ready to be written as template
+// entries. Each body is written to a file named for its slug in dir, and the
+// entry's body_file names that file relative to templateDir, where the
+// template will be written.
+func exportPages(ctx context.Context, client *canvas.Client, courseID int, dir, templateDir string) ([]*canvas.Page, error) {
+	pages, err := client.ListPages(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var lst []*canvas.Page
+	for _, elt := range pages {
+		page, err := client.GetPage(ctx, courseID, elt.URL)
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %q: %v", elt.Title, err)
+		}
+		filename := filepath.Join(dir, page.URL+".html")
+		if err = ioutil.WriteFile(filename, []byte(page.Body), 0644); err != nil {
+			return nil, err
+		}
+		log.Printf("wrote page %q to %s", page.Title, filename)
+		page.Cleanup()
+		page.Body = ""
+		page.BodyFile = relativePath(templateDir, filename)
+		lst = append(lst, page)
+	}
+	return lst, nil
+}
+
+// relativePath names filename relative to dir, so that a template written to
+// dir can refer to it. It falls back to an absolute name.
+func relativePath(dir, filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filename
+	}
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return abs
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return abs
+	}
+	return rel
+}
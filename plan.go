@@ -0,0 +1,662 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// plan actions
+const (
+	actionCreate     = "create"
+	actionUpdate     = "update"
+	actionUnchanged  = "unchanged"
+	actionRemoteOnly = "remote-only"
+	actionMissing    = "missing"
+)
+
+// planSkipFields are keys that are either template-only or maintained by
+// Canvas, so they never count as differences
+var planSkipFields = map[string]bool{
+	"id":                        true,
+	"default":                   true,
+	"extends":                   true,
+	"lock_after":                true,
+	"unlock_before":             true,
+	"peer_reviews_assign_after": true,
+	"assignments":               true,
+	"html_url":                  true,
+	"needs_grading_count":       true,
+	"has_submitted_submissions": true,
+	"unpublishable":             true,
+	"locked_for_user":           true,
+	"lock_info":                 true,
+	"lock_explanation":          true,
+	"frozen":                    true,
+	"frozen_attributes":         true,
+	"submission":                true,
+	"overrides":                 true,
+	"rubric":                    true,
+	"rubric_settings":           true,
+	"rubric_ref":                true,
+	"items":                     true,
+	"prerequisites":             true,
+	"questions":                 true,
+	"questions_file":            true,
+	"questions_format":          true,
+	"discussion_topic":          true,
+	"body":                      true,
+	"body_file":                 true,
+	"description_file":          true,
+}
+
+type planEntry struct {
+	Action  string
+	Kind    string
+	ID      int
+	Name    string
+	Changes []fieldChange
+
+	// Group and Assignment are the live versions for remote-only entries
+	Group      *canvas.AssignmentGroup
+	Assignment *canvas.Assignment
+}
+
+// planNote is a placeholder shown in place of a value in a diff line
+type planNote string
+
+type fieldChange struct {
+	Field  string
+	Remote interface{}
+	Local  interface{}
+}
+
+// plan compares the expanded entries from a template file to the live course.
+func plan(ctx context.Context, client *canvas.Client, entries []canvas.AssignmentOrGroup, courseID int) ([]*planEntry, error) {
+	groups, err := client.ListAssignmentGroups(ctx, courseID, true)
+	if err != nil {
+		return nil, err
+	}
+	remoteGroups := make(map[int]*canvas.AssignmentGroup)
+	remoteAssts := make(map[int]*canvas.Assignment)
+	for _, group := range groups {
+		remoteGroups[group.ID] = group
+		for _, asst := range group.Assignments {
+			remoteAssts[asst.ID] = asst
+		}
+	}
+
+	var remoteModules []*canvas.Module
+	var remotePages []*canvas.Page
+	var out []*planEntry
+	// a group is seen if the file has an entry for it or keeps something in it
+	seenGroups := make(map[int]bool)
+	seenAssts := make(map[int]bool)
+	seenQuizzes := make(map[int]bool)
+	stayingQuizzes := make(map[int]bool)
+	groupID, newGroup := 0, false
+	for _, aorg := range entries {
+		if aorg.Module != nil {
+			if remoteModules == nil {
+				if remoteModules, err = client.ListModules(ctx, courseID); err != nil {
+					return nil, err
+				}
+				remoteModules = append([]*canvas.Module{}, remoteModules...)
+			}
+			entry, err := planModule(ctx, client, courseID, aorg.Module, remoteModules)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, entry)
+		} else if aorg.Quiz != nil {
+			elt := aorg.Quiz
+			entry := &planEntry{Kind: "quiz", ID: elt.ID, Name: elt.Title}
+			out = append(out, entry)
+			local := *elt
+			if local.AssignmentGroupID == 0 && !newGroup {
+				local.AssignmentGroupID = groupID
+			}
+			if local.AssignmentGroupID != 0 {
+				seenGroups[local.AssignmentGroupID] = true
+			} else if !newGroup && elt.ID != 0 {
+				// a quiz with no group stays where it is
+				stayingQuizzes[elt.ID] = true
+			}
+			if elt.ID == 0 {
+				entry.Action = actionCreate
+				continue
+			}
+			seenQuizzes[elt.ID] = true
+			if entry.Changes, err = planQuiz(ctx, client, courseID, &local); err == errMissing {
+				entry.Action = actionMissing
+			} else if err != nil {
+				return nil, err
+			}
+		} else if aorg.Discussion != nil {
+			elt := aorg.Discussion
+			entry := &planEntry{Kind: "discussion", ID: elt.ID, Name: elt.Title}
+			out = append(out, entry)
+			if elt.ID == 0 {
+				entry.Action = actionCreate
+				continue
+			}
+			if entry.Changes, err = planDiscussion(ctx, client, courseID, elt); err == errMissing {
+				entry.Action = actionMissing
+			} else if err != nil {
+				return nil, err
+			}
+		} else if aorg.Page != nil {
+			if remotePages == nil {
+				if remotePages, err = client.ListPages(ctx, courseID); err != nil {
+					return nil, err
+				}
+				remotePages = append([]*canvas.Page{}, remotePages...)
+			}
+			entry, err := planPage(ctx, client, courseID, aorg.Page, remotePages)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, entry)
+		} else if aorg.Group != nil {
+			elt := aorg.Group
+			groupID, newGroup = elt.ID, elt.ID == 0
+			entry := &planEntry{Kind: "group", ID: elt.ID, Name: elt.Name}
+			out = append(out, entry)
+			if elt.ID == 0 {
+				entry.Action = actionCreate
+				continue
+			}
+			seenGroups[elt.ID] = true
+			remote, present := remoteGroups[elt.ID]
+			if !present {
+				entry.Action = actionMissing
+				continue
+			}
+			remote.Cleanup()
+			if entry.Changes, err = diffFields(elt, remote); err != nil {
+				return nil, err
+			}
+		} else if aorg.Assignment != nil {
+			elt := aorg.Assignment
+			entry := &planEntry{Kind: "assignment", ID: elt.ID, Name: elt.Name}
+			out = append(out, entry)
+
+			// compare against the group that upload would use
+			local := *elt
+			if local.AssignmentGroupID == 0 && !newGroup {
+				local.AssignmentGroupID = groupID
+			}
+			if local.AssignmentGroupID != 0 {
+				seenGroups[local.AssignmentGroupID] = true
+			}
+			if elt.ID == 0 {
+				entry.Action = actionCreate
+				continue
+			}
+			seenAssts[elt.ID] = true
+			remote, present := remoteAssts[elt.ID]
+			if !present {
+				entry.Action = actionMissing
+				continue
+			}
+			remote.Cleanup()
+			if entry.Changes, err = diffFields(&local, remote); err != nil {
+				return nil, err
+			}
+			if local.Overrides != nil {
+				changes, err := diffOverrides(local.Overrides, remote.Overrides)
+				if err != nil {
+					return nil, fmt.Errorf("assignment %q: %v", local.Name, err)
+				}
+				entry.Changes = append(entry.Changes, changes...)
+			}
+			if local.DiscussionTopic != nil {
+				changes, err := diffDiscussion(local.DiscussionTopic, remote.DiscussionTopic)
+				if err != nil {
+					return nil, fmt.Errorf("assignment %q: %v", local.Name, err)
+				}
+				entry.Changes = append(entry.Changes, changes...)
+			}
+			if local.Library != nil {
+				local.Rubric = local.Library.Criteria
+				local.RubricSettings = &canvas.RubricSettings{
+					Title:                     local.Library.Title,
+					FreeFormCriterionComments: local.Library.FreeFormCriterionComments,
+					HideScoreTotal:            local.Library.HideScoreTotal,
+				}
+			}
+			if len(local.Rubric) > 0 {
+				changes, err := diffRubric(&local, remote)
+				if err != nil {
+					return nil, fmt.Errorf("assignment %q: %v", local.Name, err)
+				}
+				entry.Changes = append(entry.Changes, changes...)
+			}
+			if newGroup && local.AssignmentGroupID == 0 {
+				entry.Changes = append(entry.Changes, fieldChange{
+					Field:  "assignment_group_id",
+					Remote: remote.AssignmentGroupID,
+					Local:  planNote("(new group)"),
+				})
+			}
+		}
+	}
+	for _, entry := range out {
+		if entry.Action == "" && len(entry.Changes) > 0 {
+			entry.Action = actionUpdate
+		} else if entry.Action == "" {
+			entry.Action = actionUnchanged
+		}
+	}
+
+	for _, group := range groups {
+		for _, asst := range group.Assignments {
+			if asst.QuizID != 0 && stayingQuizzes[asst.QuizID] {
+				seenGroups[group.ID] = true
+			}
+		}
+	}
+
+	// report anything in the course that the file does not mention
+	for _, group := range groups {
+		if !seenGroups[group.ID] {
+			out = append(out, &planEntry{Action: actionRemoteOnly, Kind: "group", ID: group.ID, Name: group.Name, Group: group})
+		}
+		for _, asst := range group.Assignments {
+			if !seenAssts[asst.ID] && !(asst.QuizID != 0 && seenQuizzes[asst.QuizID]) {
+				out = append(out, &planEntry{Action: actionRemoteOnly, Kind: "assignment", ID: asst.ID, Name: asst.Name, Assignment: asst})
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// diffFields compares every field that is set in local against remote.
+// Values are compared in their API JSON form, so timestamps are compared as instants.
+func diffFields(local, remote interface{}) ([]fieldChange, error) {
+	localFields, err := jsonFields(local)
+	if err != nil {
+		return nil, err
+	}
+	remoteFields, err := jsonFields(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range localFields {
+		if !planSkipFields[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []fieldChange
+	for _, key := range keys {
+		// Canvas leaves out some fields that are false or empty
+		if _, present := remoteFields[key]; !present && emptyJSON(localFields[key]) {
+			continue
+		}
+		if !reflect.DeepEqual(localFields[key], remoteFields[key]) {
+			changes = append(changes, fieldChange{Field: key, Remote: remoteFields[key], Local: localFields[key]})
+		}
+	}
+	return changes, nil
+}
+
+// diffOverrides matches overrides by target and compares their dates
+func diffOverrides(local, remote []*canvas.AssignmentOverride) ([]fieldChange, error) {
+	remoteByTarget := make(map[string]*canvas.AssignmentOverride)
+	for _, elt := range remote {
+		if target, err := elt.Target(); err == nil {
+			remoteByTarget[target] = elt
+		}
+	}
+
+	var changes []fieldChange
+	seen := make(map[string]bool)
+	for _, elt := range local {
+		target, err := elt.Target()
+		if err != nil {
+			return nil, err
+		}
+		seen[target] = true
+		match, present := remoteByTarget[target]
+		if !present {
+			changes = append(changes, fieldChange{Field: "overrides[" + target + "]", Remote: nil, Local: planNote("(new override)")})
+			continue
+		}
+		localOverride, remoteOverride := *elt, *match
+		localOverride.ID, remoteOverride.ID = 0, 0
+		localOverride.Cleanup()
+		remoteOverride.Cleanup()
+		fieldChanges, err := diffFields(&localOverride, &remoteOverride)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range fieldChanges {
+			change.Field = "overrides[" + target + "]." + change.Field
+			changes = append(changes, change)
+		}
+	}
+	for _, elt := range remote {
+		if target, err := elt.Target(); err == nil && !seen[target] {
+			changes = append(changes, fieldChange{Field: "overrides[" + target + "]", Remote: planNote("(override)"), Local: nil})
+		}
+	}
+	return changes, nil
+}
+
+// planModule compares a module and its items to the live version, which is
+// found by ID or else by name
+func planModule(ctx context.Context, client *canvas.Client, courseID int, elt *canvas.Module, remoteModules []*canvas.Module) (*planEntry, error) {
+	entry := &planEntry{Kind: "module", ID: elt.ID, Name: elt.Name}
+	names := make(map[int]string)
+	var remote *canvas.Module
+	for _, module := range remoteModules {
+		names[module.ID] = module.Name
+		if (elt.ID != 0 && module.ID == elt.ID) || (elt.ID == 0 && remote == nil && module.Name == elt.Name) {
+			remote = module
+		}
+	}
+	if remote == nil && elt.ID != 0 {
+		entry.Action = actionMissing
+		return entry, nil
+	} else if remote == nil {
+		entry.Action = actionCreate
+		return entry, nil
+	}
+	entry.ID = remote.ID
+
+	changes, err := diffFields(elt, remote)
+	if err != nil {
+		return nil, err
+	}
+	if elt.Prerequisites != nil {
+		var remoteNames []string
+		for _, id := range remote.PrerequisiteModuleIDs {
+			remoteNames = append(remoteNames, names[id])
+		}
+		if !reflect.DeepEqual(elt.Prerequisites, remoteNames) && (len(elt.Prerequisites) > 0 || len(remoteNames) > 0) {
+			changes = append(changes, fieldChange{Field: "prerequisites", Remote: remoteNames, Local: elt.Prerequisites})
+		}
+	}
+
+	// items are compared in order
+	if elt.Items != nil {
+		remoteItems, err := client.ListModuleItems(ctx, courseID, remote.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range elt.Items {
+			if i >= len(remoteItems) {
+				changes = append(changes, fieldChange{Field: fmt.Sprintf("items[%d]", i), Remote: nil, Local: planNote("(new item " + item.Title + ")")})
+				continue
+			}
+			localItem, remoteItem := *item, *remoteItems[i]
+			localItem.Cleanup()
+			remoteItem.Cleanup()
+			itemChanges, err := diffFields(&localItem, &remoteItem)
+			if err != nil {
+				return nil, err
+			}
+			for _, change := range itemChanges {
+				change.Field = fmt.Sprintf("items[%d].%s", i, change.Field)
+				changes = append(changes, change)
+			}
+		}
+		for i := len(elt.Items); i < len(remoteItems); i++ {
+			changes = append(changes, fieldChange{Field: fmt.Sprintf("items[%d]", i), Remote: planNote("(item " + remoteItems[i].Title + ")"), Local: nil})
+		}
+	}
+	entry.Changes = changes
+	return entry, nil
+}
+
+// errMissing reports that an object in the file is not in the course
+var errMissing = errors.New("not found in the course")
+
+// planQuiz compares a quiz and its questions to the live version
+func planQuiz(ctx context.Context, client *canvas.Client, courseID int, local *canvas.Quiz) ([]fieldChange, error) {
+	remote, err := client.GetQuiz(ctx, courseID, local.ID)
+	if apiErr, ok := err.(*canvas.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return nil, errMissing
+	} else if err != nil {
+		return nil, err
+	}
+	changes, err := diffFields(local, remote)
+	if err != nil {
+		return nil, err
+	}
+	if local.Questions == nil {
+		return changes, nil
+	}
+
+	// questions are compared in order
+	remoteQuestions, err := client.ListQuizQuestions(ctx, courseID, local.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i, question := range local.Questions {
+		if i >= len(remoteQuestions) {
+			changes = append(changes, fieldChange{Field: fmt.Sprintf("questions[%d]", i), Remote: nil, Local: planNote("(new question)")})
+			continue
+		}
+		localQuestion, remoteQuestion := *question, *remoteQuestions[i]
+		localQuestion.Cleanup()
+		remoteQuestion.Cleanup()
+		questionChanges, err := diffFields(&localQuestion, &remoteQuestion)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range questionChanges {
+			change.Field = fmt.Sprintf("questions[%d].%s", i, change.Field)
+			changes = append(changes, change)
+		}
+	}
+	for i := len(local.Questions); i < len(remoteQuestions); i++ {
+		changes = append(changes, fieldChange{Field: fmt.Sprintf("questions[%d]", i), Remote: planNote("(question)"), Local: nil})
+	}
+	return changes, nil
+}
+
+// planDiscussion compares an ungraded discussion to the live version
+func planDiscussion(ctx context.Context, client *canvas.Client, courseID int, local *canvas.DiscussionTopic) ([]fieldChange, error) {
+	remote, err := client.GetDiscussionTopic(ctx, courseID, local.ID)
+	if apiErr, ok := err.(*canvas.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return nil, errMissing
+	} else if err != nil {
+		return nil, err
+	}
+	remote.Cleanup()
+	return diffFields(local, remote)
+}
+
+// planPage compares a page to the live version, which is found by slug or else
+// by title. Canvas cleans up the HTML it is given, so a body that was uploaded
+// unchanged may still be reported as different.
+func planPage(ctx context.Context, client *canvas.Client, courseID int, elt *canvas.Page, remotePages []*canvas.Page) (*planEntry, error) {
+	entry := &planEntry{Kind: "page", Name: elt.Title}
+	match := findPage(elt, remotePages)
+	if match == nil {
+		entry.Action = actionCreate
+		return entry, nil
+	}
+	remote, err := client.GetPage(ctx, courseID, match.URL)
+	if err != nil {
+		return nil, err
+	}
+	remote.Cleanup()
+	if entry.Changes, err = diffFields(elt, remote); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(elt.Body) != strings.TrimSpace(remote.Body) {
+		entry.Changes = append(entry.Changes, fieldChange{Field: "body", Remote: planNote("(current body)"), Local: planNote("(new body)")})
+	}
+	return entry, nil
+}
+
+// diffDiscussion compares the discussion settings of a graded discussion assignment
+func diffDiscussion(local, remote *canvas.DiscussionTopic) ([]fieldChange, error) {
+	if remote == nil {
+		remote = new(canvas.DiscussionTopic)
+	}
+	fieldChanges, err := diffFields(local, remote)
+	if err != nil {
+		return nil, err
+	}
+	var changes []fieldChange
+	for _, change := range fieldChanges {
+		change.Field = "discussion_topic." + change.Field
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// diffRubric compares the rubric criteria and settings of an assignment.
+// Criterion and rating IDs are assigned by Canvas, so they are ignored.
+func diffRubric(local, remote *canvas.Assignment) ([]fieldChange, error) {
+	localCriteria, err := rubricForPlan(local.Rubric)
+	if err != nil {
+		return nil, err
+	}
+	remoteCriteria, err := rubricForPlan(remote.Rubric)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []fieldChange
+	if !reflect.DeepEqual(localCriteria, remoteCriteria) {
+		changes = append(changes, fieldChange{Field: "rubric", Remote: remoteCriteria, Local: localCriteria})
+	}
+	if local.RubricSettings == nil {
+		return changes, nil
+	}
+	localSettings := *local.RubricSettings
+	var remoteSettings canvas.RubricSettings
+	if remote.RubricSettings != nil {
+		remoteSettings = *remote.RubricSettings
+	}
+	localSettings.ID, remoteSettings.ID = 0, 0
+	localSettings.PointsPossible, remoteSettings.PointsPossible = 0, 0
+	fieldChanges, err := diffFields(&localSettings, &remoteSettings)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range fieldChanges {
+		change.Field = "rubric_settings." + change.Field
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// rubricForPlan returns the JSON form of a list of criteria with their IDs removed
+func rubricForPlan(criteria []*canvas.RubricCriteria) (interface{}, error) {
+	var stripped []canvas.RubricCriteria
+	for _, criterion := range criteria {
+		elt := *criterion
+		elt.ID = ""
+		elt.Ratings = nil
+		for _, rating := range criterion.Ratings {
+			r := *rating
+			r.ID = ""
+			elt.Ratings = append(elt.Ratings, &r)
+		}
+		stripped = append(stripped, elt)
+	}
+	raw, err := json.Marshal(stripped)
+	if err != nil {
+		return nil, fmt.Errorf("JSON error encoding rubric: %v", err)
+	}
+	var value interface{}
+	if err = json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("JSON error decoding rubric: %v", err)
+	}
+	return value, nil
+}
+
+func jsonFields(elt interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(elt)
+	if err != nil {
+		return nil, fmt.Errorf("JSON error encoding %T: %v", elt, err)
+	}
+	fields := make(map[string]interface{})
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("JSON error decoding %T: %v", elt, err)
+	}
+	return fields, nil
+}
+
+// emptyJSON reports whether a decoded JSON value is null, false, zero, or empty
+func emptyJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// printPlan writes a summary of the plan and reports whether any changes are pending.
+// If prune is set, remote-only entries count as pending deletions.
+func printPlan(w io.Writer, entries []*planEntry, prune bool) bool {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[entry.Action]++
+		if entry.ID != 0 {
+			fmt.Fprintf(w, "%-11s %s %d (%s)\n", entry.Action, entry.Kind, entry.ID, entry.Name)
+		} else {
+			fmt.Fprintf(w, "%-11s %s (%s)\n", entry.Action, entry.Kind, entry.Name)
+		}
+		for _, change := range entry.Changes {
+			fmt.Fprintf(w, "            %s: %s => %s\n", change.Field, formatValue(change.Remote), formatValue(change.Local))
+		}
+	}
+	fmt.Fprintf(w, "\n%d to create, %d to update, %d unchanged, %d remote-only",
+		counts[actionCreate], counts[actionUpdate], counts[actionUnchanged], counts[actionRemoteOnly])
+	if counts[actionMissing] > 0 {
+		fmt.Fprintf(w, ", %d missing from the course", counts[actionMissing])
+	}
+	fmt.Fprintln(w)
+
+	return counts[actionCreate] > 0 || counts[actionUpdate] > 0 || counts[actionMissing] > 0 ||
+		(prune && counts[actionRemoteOnly] > 0)
+}
+
+// formatValue renders a JSON value for a diff line, showing timestamps in local time
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "(unset)"
+	case planNote:
+		return string(v)
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t.Local().Format("2006-01-02 15:04:05")
+		}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
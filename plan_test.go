@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// fakeCanvas answers GET requests with canned JSON and records every other request
+type fakeCanvas struct {
+	t         *testing.T
+	responses map[string]string
+	requests  []string
+}
+
+func (f *fakeCanvas) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	if body, present := f.responses[key]; present {
+		w.Write([]byte(body))
+		return
+	}
+	if r.Method == "GET" {
+		f.t.Errorf("unexpected request %s", key)
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.RawQuery != "" {
+		key += "?" + r.URL.RawQuery
+	}
+	f.requests = append(f.requests, key)
+	w.Write([]byte("{}"))
+}
+
+// newFakeCanvas starts a server for course 1 with the given groups and assignments
+func newFakeCanvas(t *testing.T, groups, assignments string) (*fakeCanvas, *canvas.Client, func()) {
+	fake := &fakeCanvas{t: t, responses: map[string]string{
+		"GET /api/v1/courses/1/assignment_groups": groups,
+		"GET /api/v1/courses/1/assignments":       assignments,
+	}}
+	server := httptest.NewServer(fake)
+	client := canvas.NewClient(server.URL, "token")
+	client.MaxRetries = 0
+	return fake, client, server.Close
+}
+
+const (
+	planGroups      = `[{"id": 10, "name": "Labs", "group_weight": 20}, {"id": 20, "name": "Old"}]`
+	planAssignments = `[
+		{"id": 100, "name": "Lab 1", "assignment_group_id": 10, "points_possible": 10, "due_at": "2024-01-13T06:59:00Z"},
+		{"id": 101, "name": "Lab 2", "assignment_group_id": 10, "points_possible": 10},
+		{"id": 200, "name": "Old lab", "assignment_group_id": 20, "points_possible": 5}
+	]`
+)
+
+func TestPlan(t *testing.T) {
+	_, client, done := newFakeCanvas(t, planGroups, planAssignments)
+	defer done()
+
+	due := &canvas.Time{Time: time.Date(2024, time.January, 12, 23, 59, 0, 0, time.FixedZone("MST", -7*60*60))}
+	entries := []canvas.AssignmentOrGroup{
+		{Group: &canvas.AssignmentGroup{ID: 10, Name: "Labs", GroupWeight: 20}},
+		{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", PointsPossible: 10, DueAt: due}},
+		{Assignment: &canvas.Assignment{ID: 101, Name: "Lab 2", PointsPossible: 15}},
+		{Assignment: &canvas.Assignment{Name: "Lab 3", PointsPossible: 10}},
+		{Assignment: &canvas.Assignment{ID: 999, Name: "Gone"}},
+		{Group: &canvas.AssignmentGroup{Name: "Projects"}},
+		{Assignment: &canvas.Assignment{ID: 200, Name: "Old lab", PointsPossible: 5}},
+	}
+	steps, err := plan(context.Background(), client, entries, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, step := range steps {
+		line := step.Action + " " + step.Kind + " " + step.Name
+		for _, change := range step.Changes {
+			line += "; " + change.Field + ": " + formatValue(change.Remote) + " => " + formatValue(change.Local)
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"unchanged group Labs",
+		// the due date is the same instant in another time zone
+		"unchanged assignment Lab 1",
+		"update assignment Lab 2; points_possible: 10 => 15",
+		"create assignment Lab 3",
+		"missing assignment Gone",
+		"create group Projects",
+		"update assignment Old lab; assignment_group_id: 20 => (new group)",
+		"remote-only group Old",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got plan:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPlanRemoteOnly(t *testing.T) {
+	labs := &canvas.AssignmentGroup{ID: 10, Name: "Labs", GroupWeight: 20}
+	tests := []struct {
+		name    string
+		entries []canvas.AssignmentOrGroup
+		want    []string
+	}{
+		{
+			name: "group entry",
+			entries: []canvas.AssignmentOrGroup{
+				{Group: labs},
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", PointsPossible: 10}},
+			},
+			want: []string{"assignment Lab 2", "assignment Old lab", "group Old"},
+		},
+		{
+			name: "assignments only",
+			entries: []canvas.AssignmentOrGroup{
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{ID: 101, Name: "Lab 2", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{ID: 200, Name: "Old lab", AssignmentGroupID: 20}},
+			},
+			want: nil,
+		},
+		{
+			name: "assignments only in one group",
+			entries: []canvas.AssignmentOrGroup{
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{ID: 101, Name: "Lab 2", AssignmentGroupID: 10}},
+			},
+			want: []string{"assignment Old lab", "group Old"},
+		},
+		{
+			name: "new assignment",
+			entries: []canvas.AssignmentOrGroup{
+				{Assignment: &canvas.Assignment{Name: "Lab 3", AssignmentGroupID: 20}},
+			},
+			want: []string{"assignment Lab 1", "assignment Lab 2", "assignment Old lab", "group Labs"},
+		},
+		{
+			name: "new quiz",
+			entries: []canvas.AssignmentOrGroup{
+				{Quiz: &canvas.Quiz{Title: "Quiz 1", AssignmentGroupID: 20}},
+			},
+			want: []string{"assignment Lab 1", "assignment Lab 2", "assignment Old lab", "group Labs"},
+		},
+		{
+			name: "assignment moving to a new group",
+			entries: []canvas.AssignmentOrGroup{
+				{Group: labs},
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1"}},
+				{Assignment: &canvas.Assignment{ID: 101, Name: "Lab 2"}},
+				{Group: &canvas.AssignmentGroup{Name: "Projects"}},
+				{Assignment: &canvas.Assignment{ID: 200, Name: "Old lab"}},
+			},
+			want: []string{"group Old"},
+		},
+	}
+	for _, test := range tests {
+		_, client, done := newFakeCanvas(t, planGroups, planAssignments)
+		steps, err := plan(context.Background(), client, test.entries, 1)
+		done()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var remote []string
+		for _, step := range steps {
+			if step.Action != actionRemoteOnly {
+				continue
+			}
+			if step.Group != nil {
+				remote = append(remote, "group "+step.Group.Name)
+			} else if step.Assignment != nil {
+				remote = append(remote, "assignment "+step.Assignment.Name)
+			}
+		}
+		sort.Strings(remote)
+		if !reflect.DeepEqual(remote, test.want) {
+			t.Errorf("%s: got remote-only %q, expected %q", test.name, remote, test.want)
+		}
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	local := &canvas.Assignment{ID: 1, Name: "Lab", PointsPossible: 10, Published: true, Description: "<p>new</p>"}
+	remote := &canvas.Assignment{ID: 2, Name: "Lab", PointsPossible: 5, HTMLURL: "https://host/x", Description: "<p>old</p>"}
+	changes, err := diffFields(local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	want := []string{"description", "points_possible", "published"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got changed fields %q, expected %q", fields, want)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	tests := []struct {
+		steps   []*planEntry
+		prune   bool
+		pending bool
+		summary string
+	}{
+		{nil, false, false, "0 to create, 0 to update, 0 unchanged, 0 remote-only"},
+		{[]*planEntry{{Action: actionUnchanged}, {Action: actionRemoteOnly}}, false, false, "0 to create, 0 to update, 1 unchanged, 1 remote-only"},
+		{[]*planEntry{{Action: actionUnchanged}, {Action: actionRemoteOnly}}, true, true, "0 to create, 0 to update, 1 unchanged, 1 remote-only"},
+		{[]*planEntry{{Action: actionCreate}}, false, true, "1 to create, 0 to update, 0 unchanged, 0 remote-only"},
+		{[]*planEntry{{Action: actionUpdate}}, false, true, "0 to create, 1 to update, 0 unchanged, 0 remote-only"},
+		{[]*planEntry{{Action: actionMissing}}, false, true, "0 to create, 0 to update, 0 unchanged, 0 remote-only, 1 missing from the course"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		pending := printPlan(&buf, test.steps, test.prune)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if summary := lines[len(lines)-1]; summary != test.summary {
+			t.Errorf("got summary %q, expected %q", summary, test.summary)
+		}
+		if pending != test.pending {
+			t.Errorf("%q: pending = %v, expected %v", test.summary, pending, test.pending)
+		}
+	}
+
+	var buf bytes.Buffer
+	printPlan(&buf, []*planEntry{{Action: actionUpdate, Kind: "assignment", ID: 7, Name: "Lab", Changes: []fieldChange{
+		{Field: "points_possible", Remote: 5.0, Local: 10.0},
+		{Field: "lock_at", Remote: nil, Local: planNote("(new)")},
+	}}}, false)
+	want := "update      assignment 7 (Lab)\n" +
+		"            points_possible: 5 => 10\n" +
+		"            lock_at: (unset) => (new)\n"
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("got\n%s\nexpected it to start with\n%s", buf.String(), want)
+	}
+}
@@ -1,84 +1,226 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"time"
 
-	"github.com/imdario/mergo"
+	"github.com/russross/canvasassignments/canvas"
 )
 
-func read(filename string) []AssignmentOrGroup {
+// readFile reads the entries of a single template file, leaving include entries as they are.
+func readFile(filename string, format canvas.Format) ([]canvas.AssignmentOrGroup, error) {
 	// read the file
 	contents, err := ioutil.ReadFile(filename)
 	if err != nil {
-		log.Fatalf("Failed to read %s: %v", filename, err)
+		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
 	}
 
-	// parse the JSON
-	var results []AssignmentOrGroup
-	if err = json.Unmarshal(contents, &results); err != nil {
-		log.Fatalf("Error parsing %s: %v", filename, err)
+	// parse the JSON or YAML
+	var results []canvas.AssignmentOrGroup
+	if err = canvas.Decode(contents, &results, format); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", filename, err)
+	}
+	for i := range results {
+		results[i].Source = filename
 	}
 
-	return results
+	return results, nil
 }
 
-func applyDefaults(entries []AssignmentOrGroup, courseID int) ([]AssignmentOrGroup, int) {
-	var defaultAsst *Assignment
-	var out []AssignmentOrGroup
+// applyDefaults checks and expands the entries of a template file. Files that
+// entries refer to are relative to dir.
+//
+// An assignment is merged with the unnamed default entry before it (or the named
+// default it extends, if any), and under that with the default of its group.
+func applyDefaults(entries []canvas.AssignmentOrGroup, dir string, courseID int, sideCalendar *canvas.Calendar, sideRubrics []*canvas.LibraryRubric) (_ []canvas.AssignmentOrGroup, _ int, err error) {
+	var defaultAsst *canvas.Assignment
+	var out []canvas.AssignmentOrGroup
+
+	// errors name the file of the entry being processed, which may be an included file
+	source := ""
+	defer func() {
+		if err != nil && source != "" {
+			err = fmt.Errorf("%s: %v", source, err)
+		}
+	}()
+
+	// calendar and rubric entries apply to the whole file
+	cals := []*canvas.Calendar{sideCalendar}
+	rubrics := append([]*canvas.LibraryRubric{}, sideRubrics...)
+	for _, aorg := range entries {
+		source = aorg.Source
+		if aorg.Calendar != nil {
+			if err := checkCalendar(aorg.Calendar); err != nil {
+				return nil, 0, err
+			}
+			cals = append(cals, aorg.Calendar)
+		}
+		if aorg.Rubric != nil {
+			rubrics = append(rubrics, aorg.Rubric)
+		}
+	}
+	source = ""
+	cal := mergeCalendars(cals...)
+	library, err := rubricLibrary(rubrics)
+	if err != nil {
+		return nil, 0, err
+	}
 
+	// each series becomes its assignments, in the place of the series entry
+	var expanded []canvas.AssignmentOrGroup
 	for _, aorg := range entries {
-		if aorg.Group != nil {
-			defaultAsst = nil
+		source = aorg.Source
+		if aorg.Series == nil {
+			expanded = append(expanded, aorg)
+			continue
+		}
+		lst, err := expandSeries(cal, aorg.Series)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, asst := range lst {
+			expanded = append(expanded, canvas.AssignmentOrGroup{Assignment: asst, Source: aorg.Source})
+		}
+	}
+	entries = expanded
+	source = ""
+
+	defaults, err := collectDefaults(cal, entries)
+	if err != nil {
+		return nil, 0, err
+	}
+	var groupDefault *canvas.Assignment
+
+	modules := make(map[string]bool)
+	for _, aorg := range entries {
+		source = aorg.Source
+		if aorg.Calendar != nil || aorg.Rubric != nil {
+			continue
+		} else if aorg.Module != nil {
+			if err := checkModule(aorg.Module, modules); err != nil {
+				return nil, 0, err
+			}
+			if err := resolveTimes(cal, "module", aorg.Module.Name, aorg.Module.Times()); err != nil {
+				return nil, 0, err
+			}
+			modules[aorg.Module.Name] = true
+			out = append(out, aorg)
+		} else if aorg.Quiz != nil {
+			quiz := aorg.Quiz
+			if err := prepareQuiz(quiz); err != nil {
+				return nil, 0, err
+			}
+			if err := resolveTimes(cal, "quiz", quiz.Title, quiz.Times()); err != nil {
+				return nil, 0, err
+			}
+			if err := applyQuizCalendar(cal, quiz); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, aorg)
+		} else if aorg.Discussion != nil {
+			topic := aorg.Discussion
+			if err := prepareDiscussion(topic); err != nil {
+				return nil, 0, err
+			}
+			if err := resolveTimes(cal, "discussion", topic.Title, topic.Times()); err != nil {
+				return nil, 0, err
+			}
+			if err := applyDiscussionCalendar(cal, topic); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, aorg)
+		} else if aorg.Page != nil {
+			if err := checkPage(aorg.Page); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, aorg)
+		} else if aorg.Group != nil {
+			// the group default applies until the next group
+			groupDefault = nil
+			if aorg.Group.Default != nil {
+				if aorg.Group.Default.Default || aorg.Group.Default.Name != "" {
+					return nil, 0, fmt.Errorf("group %q: the group default cannot have a default flag or a name", aorg.Group.Name)
+				}
+				if groupDefault, err = defaults.extend(aorg.Group.Default); err != nil {
+					return nil, 0, fmt.Errorf("group %q: %v", aorg.Group.Name, err)
+				}
+			}
+			defaultAsst = groupDefault
 			out = append(out, aorg)
 		} else if aorg.Assignment != nil {
 			asst := aorg.Assignment
+			if err := resolveTimes(cal, "assignment", asst.Name, asst.Times()); err != nil {
+				return nil, 0, err
+			}
+			if asst.Description != "" && asst.DescriptionFile != "" {
+				return nil, 0, fmt.Errorf("assignment %q has both a description and a description_file", asst.Name)
+			}
 
 			// make sure there is a course ID
 			if asst.CourseID == 0 {
 				asst.CourseID = courseID
 			}
 			if asst.CourseID == 0 {
-				log.Fatalf("Unable to determine CourseID for assignment")
+				return nil, 0, errors.New("unable to determine CourseID for assignment")
 			}
 			if courseID == 0 {
 				courseID = asst.CourseID
 			}
 			if courseID != asst.CourseID {
-				log.Fatalf("CourseID mismatch from assignment: found %d but expected %d", asst.CourseID, courseID)
+				return nil, 0, fmt.Errorf("CourseID mismatch from assignment: found %d but expected %d", asst.CourseID, courseID)
+			}
+
+			// a named default is only used through extends, wherever it is in the file
+			if asst.Default && asst.Name != "" {
+				if _, err := defaults.lookup(asst.Name); err != nil {
+					return nil, 0, err
+				}
+				continue
 			}
 
 			// is this a new default
 			if asst.Default {
-				defaultAsst = asst
-
-				// clear a few values that don't belong in a default
-				defaultAsst.Default = false
-				defaultAsst.ID = 0
-				defaultAsst.Name = ""
-				defaultAsst.HTMLURL = ""
-				defaultAsst.Position = 0
-
+				def, err := defaults.extend(asst)
+				if err != nil {
+					return nil, 0, err
+				}
+				if defaultAsst, err = withGroupDefault(def, groupDefault); err != nil {
+					return nil, 0, err
+				}
 				continue
 			}
 
+			// an assignment that extends a named default uses it in place of the current default
+			def := defaultAsst
+			if asst.Extends != "" {
+				named, err := defaults.lookup(asst.Extends)
+				if err != nil {
+					return nil, 0, fmt.Errorf("assignment %q: %v", asst.Name, err)
+				}
+				if def, err = withGroupDefault(named, groupDefault); err != nil {
+					return nil, 0, err
+				}
+			}
+
 			// merge with defaults?
-			if defaultAsst != nil {
-				// merge everything
-				mergo.Merge(asst, defaultAsst)
+			if def != nil {
+				mergeDefault(asst, def)
+			}
 
-				// apply default timestamps
-				asst.DueAt = mergeDates(defaultAsst.DueAt, asst.DueAt)
-				asst.LockAt = mergeDates(defaultAsst.LockAt, asst.LockAt)
-				asst.UnlockAt = mergeDates(defaultAsst.UnlockAt, asst.UnlockAt)
-				asst.PeerReviewsAssignAt = mergeDates(defaultAsst.PeerReviewsAssignAt, asst.PeerReviewsAssignAt)
+			// move the due date off of holidays before computing relative timestamps;
+			// this is the only place it is rolled
+			if err := rollField(cal, calendarPolicy(cal), asst.Name, "due_at", &asst.DueAt); err != nil {
+				return nil, 0, err
+			}
 
+			if def != nil {
 				// apply relative timestamps
 				asst.LockAt = applyAfter(asst.LockAt, asst.DueAt, asst.LockAfter)
 				if asst.UnlockBefore != nil {
-					asst.UnlockBefore.Duration = -asst.UnlockBefore.Duration
+					// the duration may be shared with the default, so negate a copy
+					asst.UnlockBefore = &canvas.Duration{Duration: -asst.UnlockBefore.Duration}
 				}
 				asst.UnlockAt = applyAfter(asst.UnlockAt, asst.DueAt, asst.UnlockBefore)
 				asst.PeerReviewsAssignAt = applyAfter(asst.PeerReviewsAssignAt, asst.DueAt, asst.PeerReviewsAssignAfter)
@@ -87,6 +229,9 @@ func applyDefaults(entries []AssignmentOrGroup, courseID int) ([]AssignmentOrGro
 				asst.UnlockBefore = nil
 				asst.PeerReviewsAssignAfter = nil
 
+				// an explicit null beats a time computed from the default
+				asst.Explicit.Reset(asst)
+
 				/*
 					// copy other defaults
 					asst.Name = mergeString(defaultAsst.Name, asst.Name)
@@ -103,20 +248,30 @@ func applyDefaults(entries []AssignmentOrGroup, courseID int) ([]AssignmentOrGro
 					asst.GradeGroupStudentsIndividually = defaultAsst.GradeGroupStudentsIndividually || asst.GradeGroupStudentsIndividually
 					asst.ExternalToolTagAttributes = mergeETTA(defaultAsst.ExternalToolTagAttributes, asst.ExternalToolTagAttributes)
 				*/
+			}
 
-				out = append(out, AssignmentOrGroup{Assignment: asst})
-			} else {
-				out = append(out, AssignmentOrGroup{Assignment: asst})
+			if err := loadDescription(asst, dir); err != nil {
+				return nil, 0, err
+			}
+			if err := prepareGradedDiscussion(asst); err != nil {
+				return nil, 0, err
 			}
+			if err := applyCalendar(cal, asst); err != nil {
+				return nil, 0, err
+			}
+			if err := resolveRubricRef(library, asst); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, canvas.AssignmentOrGroup{Assignment: asst, Source: aorg.Source})
 		} else {
-			log.Fatalf("AssignmentOrGroup entry that is neither assignment nor group")
+			return nil, 0, errors.New("AssignmentOrGroup entry that is not an assignment, group, calendar, rubric, module, quiz, discussion, page, or series")
 		}
 	}
 
-	return out, courseID
+	return out, courseID, nil
 }
 
-func mergeDates(def, actual *jsonTime) *jsonTime {
+func mergeDates(def, actual *canvas.Time) *canvas.Time {
 	if def == nil && actual == nil {
 		return nil
 	}
@@ -136,21 +291,21 @@ func mergeDates(def, actual *jsonTime) *jsonTime {
 		out = time.Date(year, month, day, hour, minute, second, 0, time.Local)
 	}
 
-	return &jsonTime{out}
+	return &canvas.Time{Time: out}
 }
 
-func mergeAfter(def, actual *jsonDuration) *jsonDuration {
+func mergeAfter(def, actual *canvas.Duration) *canvas.Duration {
 	if actual != nil {
 		return actual
 	}
 	return def
 }
 
-func applyAfter(actual, at *jsonTime, after *jsonDuration) *jsonTime {
+func applyAfter(actual, at *canvas.Time, after *canvas.Duration) *canvas.Time {
 	if actual != nil || after == nil || at == nil {
 		return actual
 	}
-	return &jsonTime{at.Add(after.Duration)}
+	return &canvas.Time{Time: at.Add(after.Duration)}
 }
 
 func mergeString(def, actual string) string {
@@ -184,3 +339,15 @@ func mergeStringSlice(def, actual []string) []string {
 	}
 	return lst
 }
+
+func copyOverrides(overrides []*canvas.AssignmentOverride) []*canvas.AssignmentOverride {
+	if overrides == nil {
+		return nil
+	}
+	out := []*canvas.AssignmentOverride{}
+	for _, elt := range overrides {
+		override := *elt
+		out = append(out, &override)
+	}
+	return out
+}
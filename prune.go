@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// pruneList is the set of live objects that a template file does not cover
+type pruneList struct {
+	Groups      []*canvas.AssignmentGroup
+	Assignments []*canvas.Assignment
+
+	// Refused are assignments that would be deleted but already have submissions
+	Refused []*canvas.Assignment
+}
+
+// findPrunable collects the remote-only entries of a plan. Assignments with
+// submissions are refused unless force is set.
+func findPrunable(steps []*planEntry, force bool) *pruneList {
+	list := new(pruneList)
+	for _, step := range steps {
+		if step.Action != actionRemoteOnly {
+			continue
+		}
+		switch {
+		case step.Group != nil:
+			list.Groups = append(list.Groups, step.Group)
+		case step.Assignment != nil && step.Assignment.HasSubmittedSubmissions && !force:
+			list.Refused = append(list.Refused, step.Assignment)
+		case step.Assignment != nil:
+			list.Assignments = append(list.Assignments, step.Assignment)
+		}
+	}
+	return list
+}
+
+func (list *pruneList) Empty() bool {
+	return len(list.Groups) == 0 && len(list.Assignments) == 0
+}
+
+// Print lists everything that prune will delete or refuse to delete.
+func (list *pruneList) Print(w io.Writer) {
+	for _, elt := range list.Assignments {
+		fmt.Fprintf(w, "delete assignment %d (%s)\n", elt.ID, elt.Name)
+	}
+	for _, elt := range list.Groups {
+		fmt.Fprintf(w, "delete group %d (%s)\n", elt.ID, elt.Name)
+	}
+	for _, elt := range list.Refused {
+		fmt.Fprintf(w, "keep assignment %d (%s): it has submissions (use -force to delete it anyway)\n", elt.ID, elt.Name)
+	}
+}
+
+// Confirm lists the deletions and asks the user to approve them.
+func (list *pruneList) Confirm(in io.Reader, out io.Writer) (bool, error) {
+	list.Print(out)
+	fmt.Fprintf(out, "Delete %d assignments and %d groups? [y/N] ", len(list.Assignments), len(list.Groups))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("error reading confirmation: %v", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes", nil
+}
+
+// prune deletes the assignments and then the groups in the list. A group that
+// still holds assignments that are not being deleted, including any that upload
+// just placed in it, has them moved to moveTo first; without moveTo the group is kept
+// and prune stops with an error.
+func prune(ctx context.Context, client *canvas.Client, courseID int, list *pruneList, moveTo int) error {
+	deleted := make(map[int]bool)
+	for _, elt := range list.Assignments {
+		log.Printf("deleting assignment %d (%s)", elt.ID, elt.Name)
+		if err := client.DeleteAssignment(ctx, courseID, elt.ID); err != nil {
+			return fmt.Errorf("deleting assignment %q: %v", elt.Name, err)
+		}
+		deleted[elt.ID] = true
+	}
+	if len(list.Groups) == 0 {
+		return nil
+	}
+
+	// look again, since upload may have moved assignments since the plan was made
+	groups, err := client.ListAssignmentGroups(ctx, courseID, true)
+	if err != nil {
+		return err
+	}
+	current := make(map[int]*canvas.AssignmentGroup)
+	for _, group := range groups {
+		current[group.ID] = group
+	}
+
+	for _, group := range list.Groups {
+		// does this group still hold assignments that we are keeping?
+		kept := append([]*canvas.Assignment{}, list.Refused...)
+		if live := current[group.ID]; live != nil {
+			kept = append(kept, live.Assignments...)
+		}
+		target := 0
+		for _, elt := range kept {
+			if elt.AssignmentGroupID != group.ID || deleted[elt.ID] {
+				continue
+			}
+			why := "that is being kept"
+			if elt.HasSubmittedSubmissions {
+				why = "with submissions"
+			}
+			if moveTo == 0 || moveTo == group.ID {
+				return fmt.Errorf("group %d (%s) still holds assignment %d (%s) %s; use -move_assignments_to",
+					group.ID, group.Name, elt.ID, elt.Name, why)
+			}
+			target = moveTo
+		}
+
+		if target != 0 {
+			log.Printf("deleting group %d (%s) and moving its assignments to group %d", group.ID, group.Name, target)
+		} else {
+			log.Printf("deleting group %d (%s)", group.ID, group.Name)
+		}
+		if err := client.DeleteAssignmentGroup(ctx, courseID, group.ID, target); err != nil {
+			return fmt.Errorf("deleting group %q: %v", group.Name, err)
+		}
+	}
+	return nil
+}
+
+// firstGroupID returns the ID of the first group in a list of entries, or 0 if there is none
+func firstGroupID(entries []canvas.AssignmentOrGroup) int {
+	for _, aorg := range entries {
+		if aorg.Group != nil && aorg.Group.ID != 0 {
+			return aorg.Group.ID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestFindPrunable(t *testing.T) {
+	old := &canvas.AssignmentGroup{ID: 20, Name: "Old"}
+	quiet := &canvas.Assignment{ID: 200, Name: "Unused", AssignmentGroupID: 20}
+	submitted := &canvas.Assignment{ID: 201, Name: "Graded", AssignmentGroupID: 20, HasSubmittedSubmissions: true}
+	steps := []*planEntry{
+		{Action: actionUnchanged, Kind: "group", ID: 10, Name: "Labs"},
+		{Action: actionUpdate, Kind: "assignment", ID: 100, Name: "Lab 1"},
+		{Action: actionCreate, Kind: "assignment", Name: "Lab 2"},
+		{Action: actionMissing, Kind: "assignment", ID: 999, Name: "Gone"},
+		{Action: actionRemoteOnly, Kind: "group", ID: 20, Name: "Old", Group: old},
+		{Action: actionRemoteOnly, Kind: "assignment", ID: 200, Name: "Unused", Assignment: quiet},
+		{Action: actionRemoteOnly, Kind: "assignment", ID: 201, Name: "Graded", Assignment: submitted},
+	}
+	tests := []struct {
+		force bool
+		want  *pruneList
+	}{
+		{false, &pruneList{
+			Groups:      []*canvas.AssignmentGroup{old},
+			Assignments: []*canvas.Assignment{quiet},
+			Refused:     []*canvas.Assignment{submitted},
+		}},
+		{true, &pruneList{
+			Groups:      []*canvas.AssignmentGroup{old},
+			Assignments: []*canvas.Assignment{quiet, submitted},
+		}},
+	}
+	for _, test := range tests {
+		if got := findPrunable(steps, test.force); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("force=%v: got %+v, expected %+v", test.force, got, test.want)
+		}
+	}
+
+	if list := findPrunable(steps[:4], false); !list.Empty() {
+		t.Errorf("a plan with nothing remote-only gave %+v", list)
+	}
+}
+
+func TestFindPrunableFromPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []canvas.AssignmentOrGroup
+		want    string
+	}{
+		{
+			name: "assignments only",
+			entries: []canvas.AssignmentOrGroup{
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{ID: 101, Name: "Lab 2", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{ID: 200, Name: "Old lab", AssignmentGroupID: 20}},
+			},
+			want: "",
+		},
+		{
+			name: "assignments only in one group",
+			entries: []canvas.AssignmentOrGroup{
+				{Assignment: &canvas.Assignment{ID: 100, Name: "Lab 1", AssignmentGroupID: 10}},
+				{Assignment: &canvas.Assignment{Name: "Lab 3", AssignmentGroupID: 10}},
+			},
+			want: "delete assignment 101 (Lab 2)\n" +
+				"delete assignment 200 (Old lab)\n" +
+				"delete group 20 (Old)\n",
+		},
+		{
+			name: "group entry",
+			entries: []canvas.AssignmentOrGroup{
+				{Group: &canvas.AssignmentGroup{ID: 20, Name: "Old"}},
+				{Assignment: &canvas.Assignment{ID: 200, Name: "Old lab"}},
+			},
+			want: "delete assignment 100 (Lab 1)\n" +
+				"delete assignment 101 (Lab 2)\n" +
+				"delete group 10 (Labs)\n",
+		},
+	}
+	for _, test := range tests {
+		_, client, done := newFakeCanvas(t, planGroups, planAssignments)
+		steps, err := plan(context.Background(), client, test.entries, 1)
+		done()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		findPrunable(steps, false).Print(&buf)
+		if buf.String() != test.want {
+			t.Errorf("%s: got\n%s\nexpected\n%s", test.name, buf.String(), test.want)
+		}
+	}
+}
+
+func TestPruneConfirm(t *testing.T) {
+	list := &pruneList{
+		Groups:      []*canvas.AssignmentGroup{{ID: 20, Name: "Old"}},
+		Assignments: []*canvas.Assignment{{ID: 200, Name: "Unused"}},
+		Refused:     []*canvas.Assignment{{ID: 201, Name: "Graded"}},
+	}
+	tests := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		" y ":   true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"yep\n": false,
+	}
+	for in, want := range tests {
+		var out bytes.Buffer
+		got, err := list.Confirm(strings.NewReader(in), &out)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+		} else if got != want {
+			t.Errorf("%q: got %v, expected %v", in, got, want)
+		}
+		prompt := "delete assignment 200 (Unused)\n" +
+			"delete group 20 (Old)\n" +
+			"keep assignment 201 (Graded): it has submissions (use -force to delete it anyway)\n" +
+			"Delete 1 assignments and 1 groups? [y/N] "
+		if out.String() != prompt {
+			t.Errorf("%q: got prompt %q", in, out.String())
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	unused := &canvas.Assignment{ID: 200, Name: "Unused", AssignmentGroupID: 20}
+	graded := &canvas.Assignment{ID: 201, Name: "Graded", AssignmentGroupID: 20, HasSubmittedSubmissions: true}
+	groups := []*canvas.AssignmentGroup{{ID: 20, Name: "Old"}, {ID: 30, Name: "Empty"}}
+	const liveGroups = `[{"id": 20, "name": "Old"}, {"id": 30, "name": "Empty"}]`
+	tests := []struct {
+		name    string
+		list    *pruneList
+		live    string
+		moveTo  int
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "delete everything",
+			list:   &pruneList{Groups: groups, Assignments: []*canvas.Assignment{unused, graded}},
+			moveTo: 10,
+			want: []string{
+				"DELETE /api/v1/courses/1/assignments/200",
+				"DELETE /api/v1/courses/1/assignments/201",
+				"DELETE /api/v1/courses/1/assignment_groups/20",
+				"DELETE /api/v1/courses/1/assignment_groups/30",
+			},
+		},
+		{
+			name:   "move kept assignments",
+			list:   &pruneList{Groups: groups, Assignments: []*canvas.Assignment{unused}, Refused: []*canvas.Assignment{graded}},
+			moveTo: 10,
+			want: []string{
+				"DELETE /api/v1/courses/1/assignments/200",
+				"DELETE /api/v1/courses/1/assignment_groups/20?move_assignments_to=10",
+				"DELETE /api/v1/courses/1/assignment_groups/30",
+			},
+		},
+		{
+			name:    "nowhere to move kept assignments",
+			list:    &pruneList{Groups: groups, Refused: []*canvas.Assignment{graded}},
+			wantErr: "group 20 (Old) still holds assignment 201 (Graded) with submissions",
+		},
+		{
+			// upload put a new assignment in a group that was empty when the plan was made
+			name:   "move assignments placed by upload",
+			list:   &pruneList{Groups: groups[1:]},
+			live:   `[{"id": 300, "name": "New lab", "assignment_group_id": 30}]`,
+			moveTo: 10,
+			want: []string{
+				"DELETE /api/v1/courses/1/assignment_groups/30?move_assignments_to=10",
+			},
+		},
+		{
+			name:    "nowhere to move assignments placed by upload",
+			list:    &pruneList{Groups: groups[1:]},
+			live:    `[{"id": 300, "name": "New lab", "assignment_group_id": 30}]`,
+			wantErr: "group 30 (Empty) still holds assignment 300 (New lab) that is being kept",
+		},
+		{
+			name:    "moving assignments into the deleted group",
+			list:    &pruneList{Groups: groups[1:]},
+			live:    `[{"id": 300, "name": "New lab", "assignment_group_id": 30}]`,
+			moveTo:  30,
+			wantErr: "group 30 (Empty) still holds assignment 300 (New lab)",
+		},
+	}
+	for _, test := range tests {
+		live := test.live
+		if live == "" {
+			live = "[]"
+		}
+		fake, client, done := newFakeCanvas(t, liveGroups, live)
+		err := prune(context.Background(), client, 1, test.list, test.moveTo)
+		done()
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %v, expected %q", test.name, err, test.wantErr)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(fake.requests, test.want) {
+			t.Errorf("%s: got requests\n%s\nexpected\n%s", test.name, strings.Join(fake.requests, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// plain-text question file formats
+const (
+	formatGIFT  = "gift"
+	formatAiken = "aiken"
+)
+
+// giftPoints is the score given to each question read from a question file,
+// since neither format has a way to give one
+const giftPoints = 1
+
+// loadQuestionFiles reads the questions_file of every quiz entry and adds its
+// questions after any that are written out in the entry. Relative file names
+// are relative to dir, which is normally the directory of the template file.
+func loadQuestionFiles(entries []canvas.AssignmentOrGroup, dir string) error {
+	for _, aorg := range entries {
+		quiz := aorg.Quiz
+		if quiz == nil || quiz.QuestionsFile == "" {
+			continue
+		}
+		filename := quiz.QuestionsFile
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		questions, err := readQuestions(filename, quiz.QuestionsFormat)
+		if err != nil {
+			return fmt.Errorf("quiz %q: %v", quiz.Title, err)
+		}
+		quiz.Questions = append(quiz.Questions, questions...)
+	}
+	return nil
+}
+
+var aikenAnswerLine = regexp.MustCompile(`^ANSWER:\s*([A-Za-z])\s*$`)
+var aikenOptionLine = regexp.MustCompile(`^([A-Za-z])[.)]\s+(.*)$`)
+
+// readQuestions parses a question file in GIFT or Aiken format. If format is
+// empty, it is chosen by the file extension or else by looking at the contents.
+func readQuestions(filename, format string) ([]*canvas.QuizQuestion, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+	text := strings.Replace(string(contents), "\r\n", "\n", -1)
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".gift":
+			format = formatGIFT
+		case ".aiken":
+			format = formatAiken
+		default:
+			format = formatGIFT
+			for _, line := range strings.Split(text, "\n") {
+				if aikenAnswerLine.MatchString(strings.TrimSpace(line)) {
+					format = formatAiken
+					break
+				}
+			}
+		}
+	}
+
+	switch format {
+	case formatGIFT:
+		return parseGIFT(filename, text)
+	case formatAiken:
+		return parseAiken(filename, text)
+	default:
+		return nil, fmt.Errorf("unknown question file format %q: expected %s or %s", format, formatGIFT, formatAiken)
+	}
+}
+
+// parseAiken parses questions in Aiken format: the question text, lettered
+// options like "A. text" or "A) text", and a line like "ANSWER: B".
+func parseAiken(filename, text string) ([]*canvas.QuizQuestion, error) {
+	var questions []*canvas.QuizQuestion
+	var question *canvas.QuizQuestion
+	var letters []string
+	start := 0
+	for i, line := range strings.Split(text, "\n") {
+		lineNumber := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if question != nil && len(letters) > 0 {
+				return nil, fmt.Errorf("%s:%d: blank line before the ANSWER line of the question that starts on line %d", filename, lineNumber, start)
+			}
+			continue
+		}
+		if question == nil {
+			question = &canvas.QuizQuestion{
+				QuestionName:   fmt.Sprintf("Question %d", len(questions)+1),
+				QuestionType:   canvas.MultipleChoice,
+				PointsPossible: giftPoints,
+			}
+			letters = nil
+			start = lineNumber
+		}
+
+		if match := aikenAnswerLine.FindStringSubmatch(line); match != nil {
+			if len(letters) < 2 {
+				return nil, fmt.Errorf("%s:%d: question needs at least two options before its ANSWER line", filename, lineNumber)
+			}
+			found := false
+			for j, letter := range letters {
+				if strings.EqualFold(letter, match[1]) {
+					question.Answers[j].Weight = canvas.CorrectWeight
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%s:%d: answer %s is not one of the options", filename, lineNumber, match[1])
+			}
+			questions = append(questions, question)
+			question = nil
+		} else if match := aikenOptionLine.FindStringSubmatch(line); match != nil && question.QuestionText != "" {
+			letters = append(letters, match[1])
+			question.Answers = append(question.Answers, &canvas.QuizAnswer{Text: match[2]})
+		} else if len(letters) > 0 {
+			return nil, fmt.Errorf("%s:%d: expected an option like \"C. text\" or an ANSWER line", filename, lineNumber)
+		} else if question.QuestionText == "" {
+			question.QuestionText = line
+		} else {
+			question.QuestionText += "\n" + line
+		}
+	}
+	if question != nil {
+		return nil, fmt.Errorf("%s:%d: question has no ANSWER line", filename, start)
+	}
+	return questions, nil
+}
+
+// giftReader tracks the position within a GIFT file so that errors can name a line
+type giftReader struct {
+	filename string
+	text     string
+}
+
+func (r *giftReader) errorf(offset int, format string, args ...interface{}) error {
+	line := strings.Count(r.text[:offset], "\n") + 1
+	return fmt.Errorf("%s:%d: %s", r.filename, line, fmt.Sprintf(format, args...))
+}
+
+// parseGIFT parses questions in Moodle's GIFT format. Questions are separated
+// by blank lines, and each has an optional ::title::, the question text, and an
+// answer block in braces:
+//
+//	{=right ~wrong ~wrong}         multiple choice (~%50%partly right gives multiple answers)
+//	{T} or {FALSE}                 true/false
+//	{=answer =other answer}        short answer
+//	{=left -> right =a -> b}       matching
+//	{#3.14:0.01} or {#1..5}        numerical
+//	{}                             essay
+//
+// Any answer can be followed by #feedback, and the block can end with
+// ####general feedback. A question with no answer block is text only.
+func parseGIFT(filename, text string) ([]*canvas.QuizQuestion, error) {
+	r := &giftReader{filename: filename, text: text}
+
+	// blank out comments and category lines so offsets still match the file
+	lines := strings.SplitAfter(text, "\n")
+	var clean strings.Builder
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "$CATEGORY:") {
+			clean.WriteString(strings.Repeat(" ", len(line)-1))
+			if strings.HasSuffix(line, "\n") {
+				clean.WriteString("\n")
+			} else {
+				clean.WriteString(" ")
+			}
+			continue
+		}
+		clean.WriteString(line)
+	}
+	body := clean.String()
+
+	// split into questions at blank lines that are outside of answer blocks
+	var questions []*canvas.QuizQuestion
+	start, depth, blank := -1, 0, true
+	for i := 0; i <= len(body); i++ {
+		if i == len(body) || (body[i] == '\n' && depth == 0 && blank && start >= 0) {
+			if start >= 0 {
+				if depth > 0 {
+					return nil, r.errorf(start, "answer block is not closed")
+				}
+				question, err := r.parseQuestion(start, strings.TrimRight(body[start:i], " \t\n"))
+				if err != nil {
+					return nil, err
+				}
+				if question.QuestionName == "" {
+					question.QuestionName = fmt.Sprintf("Question %d", len(questions)+1)
+				}
+				questions = append(questions, question)
+				start = -1
+			}
+			continue
+		}
+		switch c := body[i]; c {
+		case '\n':
+			blank = true
+		case ' ', '\t', '\r':
+		default:
+			if start < 0 {
+				start = i
+			}
+			blank = false
+			switch {
+			case c == '\\' && i+1 < len(body):
+				i++
+			case c == '{':
+				depth++
+			case c == '}':
+				depth--
+				if depth < 0 {
+					return nil, r.errorf(i, "unexpected }")
+				}
+			}
+		}
+	}
+	return questions, nil
+}
+
+// indexUnescaped finds the first occurrence of sep in s that is not preceded by a backslash
+func indexUnescaped(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+var giftEscapes = strings.NewReplacer(`\~`, "~", `\=`, "=", `\#`, "#", `\{`, "{", `\}`, "}", `\:`, ":", `\n`, "\n", `\\`, `\`)
+
+func unescapeGIFT(s string) string {
+	return strings.TrimSpace(giftEscapes.Replace(s))
+}
+
+var giftFormats = []string{"[html]", "[moodle]", "[plain]", "[markdown]"}
+
+// parseQuestion parses one question that starts at offset start in the file
+func (r *giftReader) parseQuestion(start int, text string) (*canvas.QuizQuestion, error) {
+	question := &canvas.QuizQuestion{PointsPossible: giftPoints}
+	pos := 0
+
+	// optional title
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return nil, r.errorf(start, "question title is not closed with ::")
+		}
+		question.QuestionName = unescapeGIFT(text[2 : 2+end])
+		pos = 2 + end + 2
+	}
+	rest := strings.TrimLeft(text[pos:], " \t\n")
+	pos = len(text) - len(rest)
+	for _, marker := range giftFormats {
+		if strings.HasPrefix(rest, marker) {
+			pos += len(marker)
+			break
+		}
+	}
+
+	open := indexUnescaped(text[pos:], "{")
+	if open < 0 {
+		question.QuestionType = "text_only_question"
+		question.PointsPossible = 0
+		question.QuestionText = unescapeGIFT(text[pos:])
+		return question, nil
+	}
+	open += pos
+	close := indexUnescaped(text[open:], "}")
+	if close < 0 {
+		return nil, r.errorf(start+open, "answer block is not closed")
+	}
+	close += open
+
+	// text after the answer block makes it a fill-in-the-blank question
+	question.QuestionText = unescapeGIFT(text[pos:open])
+	if after := unescapeGIFT(text[close+1:]); after != "" {
+		question.QuestionText += " _____ " + after
+	}
+	if question.QuestionText == "" {
+		return nil, r.errorf(start, "question has no text")
+	}
+
+	block := text[open+1 : close]
+	blockStart := start + open + 1
+	if general := indexUnescaped(block, "####"); general >= 0 {
+		question.NeutralComments = unescapeGIFT(block[general+4:])
+		block = block[:general]
+	}
+	trimmed := strings.TrimSpace(block)
+
+	var err error
+	switch upper := strings.ToUpper(strings.TrimSpace(strings.SplitN(trimmed, "#", 2)[0])); {
+	case trimmed == "":
+		question.QuestionType = canvas.Essay
+	case strings.HasPrefix(trimmed, "#"):
+		err = r.parseNumerical(question, block, blockStart)
+	case upper == "T" || upper == "TRUE" || upper == "F" || upper == "FALSE":
+		r.parseTrueFalse(question, trimmed)
+	default:
+		err = r.parseChoices(question, block, blockStart)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// parseTrueFalse handles {T}, {FALSE}, etc., with optional #wrong feedback#right feedback
+func (r *giftReader) parseTrueFalse(question *canvas.QuizQuestion, block string) {
+	question.QuestionType = canvas.TrueFalse
+	parts := splitUnescaped(block, "#")
+	isTrue := strings.HasPrefix(strings.ToUpper(strings.TrimSpace(parts[0])), "T")
+	if len(parts) > 1 {
+		question.IncorrectComments = unescapeGIFT(parts[1])
+	}
+	if len(parts) > 2 {
+		question.CorrectComments = unescapeGIFT(parts[2])
+	}
+	trueAnswer := &canvas.QuizAnswer{Text: "True"}
+	falseAnswer := &canvas.QuizAnswer{Text: "False"}
+	if isTrue {
+		trueAnswer.Weight = canvas.CorrectWeight
+	} else {
+		falseAnswer.Weight = canvas.CorrectWeight
+	}
+	question.Answers = []*canvas.QuizAnswer{trueAnswer, falseAnswer}
+}
+
+// splitUnescaped splits s at every occurrence of sep that is not preceded by a backslash
+func splitUnescaped(s, sep string) []string {
+	var parts []string
+	for {
+		i := indexUnescaped(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+// giftChoice is one =answer or ~answer in an answer block
+type giftChoice struct {
+	right    bool
+	weight   float64
+	text     string
+	feedback string
+	offset   int
+}
+
+// splitChoices breaks an answer block into its = and ~ answers
+func (r *giftReader) splitChoices(block string, blockStart int) ([]*giftChoice, error) {
+	var choices []*giftChoice
+	var current *giftChoice
+	var text strings.Builder
+	flush := func() {
+		if current != nil {
+			current.text = text.String()
+			choices = append(choices, current)
+		}
+		text.Reset()
+	}
+	for i := 0; i < len(block); i++ {
+		c := block[i]
+		switch {
+		case c == '\\' && i+1 < len(block):
+			text.WriteString(block[i : i+2])
+			i++
+		case c == '=' || c == '~':
+			flush()
+			current = &giftChoice{right: c == '=', offset: blockStart + i}
+			if c == '=' {
+				current.weight = canvas.CorrectWeight
+			}
+		case current == nil:
+			if c != ' ' && c != '\t' && c != '\n' {
+				return nil, r.errorf(blockStart+i, "expected an answer starting with = or ~")
+			}
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flush()
+
+	for _, choice := range choices {
+		// optional %weight%
+		if strings.HasPrefix(choice.text, "%") {
+			end := strings.Index(choice.text[1:], "%")
+			if end < 0 {
+				return nil, r.errorf(choice.offset, "answer weight is not closed with %%")
+			}
+			weight, err := strconv.ParseFloat(choice.text[1:1+end], 64)
+			if err != nil {
+				return nil, r.errorf(choice.offset, "invalid answer weight %q", choice.text[1:1+end])
+			}
+			choice.weight = weight
+			choice.text = choice.text[end+2:]
+		}
+		parts := splitUnescaped(choice.text, "#")
+		choice.text = parts[0]
+		if len(parts) > 1 {
+			choice.feedback = unescapeGIFT(strings.Join(parts[1:], "#"))
+		}
+	}
+	return choices, nil
+}
+
+// parseChoices handles multiple choice, multiple answer, short answer, and matching blocks
+func (r *giftReader) parseChoices(question *canvas.QuizQuestion, block string, blockStart int) error {
+	choices, err := r.splitChoices(block, blockStart)
+	if err != nil {
+		return err
+	}
+	if len(choices) == 0 {
+		return r.errorf(blockStart, "answer block has no answers")
+	}
+
+	rights, wrongs, partial, matching := 0, 0, false, false
+	for _, choice := range choices {
+		if choice.right {
+			rights++
+		} else {
+			wrongs++
+			if choice.weight > 0 {
+				partial = true
+			}
+		}
+		if indexUnescaped(choice.text, "->") >= 0 {
+			matching = true
+		}
+	}
+
+	switch {
+	case matching:
+		question.QuestionType = canvas.Matching
+		var distractors []string
+		for _, choice := range choices {
+			arrow := indexUnescaped(choice.text, "->")
+			if !choice.right || arrow < 0 {
+				return r.errorf(choice.offset, "matching answers must look like =left -> right")
+			}
+			left, right := unescapeGIFT(choice.text[:arrow]), unescapeGIFT(choice.text[arrow+2:])
+			if left == "" {
+				distractors = append(distractors, right)
+				continue
+			}
+			question.Answers = append(question.Answers, &canvas.QuizAnswer{Left: left, Right: right, Comments: choice.feedback})
+		}
+		question.MatchingAnswerIncorrectMatches = strings.Join(distractors, "\n")
+	case wrongs == 0:
+		question.QuestionType = canvas.ShortAnswer
+		for _, choice := range choices {
+			question.Answers = append(question.Answers, &canvas.QuizAnswer{Text: unescapeGIFT(choice.text), Weight: canvas.CorrectWeight, Comments: choice.feedback})
+		}
+	case partial && rights == 0:
+		question.QuestionType = "multiple_answers_question"
+		for _, choice := range choices {
+			answer := &canvas.QuizAnswer{Text: unescapeGIFT(choice.text), Comments: choice.feedback}
+			if choice.weight > 0 {
+				answer.Weight = canvas.CorrectWeight
+			}
+			question.Answers = append(question.Answers, answer)
+		}
+	case rights == 1:
+		question.QuestionType = canvas.MultipleChoice
+		for _, choice := range choices {
+			answer := &canvas.QuizAnswer{Text: unescapeGIFT(choice.text), Comments: choice.feedback}
+			if choice.right {
+				answer.Weight = canvas.CorrectWeight
+			}
+			question.Answers = append(question.Answers, answer)
+		}
+	default:
+		return r.errorf(blockStart, "multiple choice answers need exactly one =right answer, or ~%%n%% weights for more than one")
+	}
+	return nil
+}
+
+// parseNumerical handles {#value}, {#value:margin}, {#low..high}, and {#=a =%50%b} blocks
+func (r *giftReader) parseNumerical(question *canvas.QuizQuestion, block string, blockStart int) error {
+	question.QuestionType = canvas.Numerical
+	hash := strings.Index(block, "#")
+	body := block[hash+1:]
+	bodyStart := blockStart + hash + 1
+
+	var choices []*giftChoice
+	if strings.HasPrefix(strings.TrimSpace(body), "=") {
+		var err error
+		if choices, err = r.splitChoices(body, bodyStart); err != nil {
+			return err
+		}
+	} else {
+		parts := splitUnescaped(body, "#")
+		choice := &giftChoice{right: true, weight: canvas.CorrectWeight, text: parts[0], offset: bodyStart}
+		if len(parts) > 1 {
+			choice.feedback = unescapeGIFT(strings.Join(parts[1:], "#"))
+		}
+		choices = append(choices, choice)
+	}
+
+	for _, choice := range choices {
+		if !choice.right {
+			return r.errorf(choice.offset, "numerical answers start with =, not ~")
+		}
+		answer := &canvas.QuizAnswer{Weight: choice.weight, Comments: choice.feedback}
+		value := strings.TrimSpace(choice.text)
+		var err error
+		if i := strings.Index(value, ".."); i >= 0 {
+			answer.NumericalAnswerType = canvas.RangeAnswer
+			if answer.Start, err = strconv.ParseFloat(strings.TrimSpace(value[:i]), 64); err == nil {
+				answer.End, err = strconv.ParseFloat(strings.TrimSpace(value[i+2:]), 64)
+			}
+		} else if i := strings.Index(value, ":"); i >= 0 {
+			answer.NumericalAnswerType = canvas.ExactAnswer
+			if answer.Exact, err = strconv.ParseFloat(strings.TrimSpace(value[:i]), 64); err == nil {
+				answer.Margin, err = strconv.ParseFloat(strings.TrimSpace(value[i+1:]), 64)
+			}
+		} else {
+			answer.NumericalAnswerType = canvas.ExactAnswer
+			answer.Exact, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			return r.errorf(choice.offset, "invalid numerical answer %q", value)
+		}
+		question.Answers = append(question.Answers, answer)
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestParseGIFT(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want *canvas.QuizQuestion
+	}{
+		{
+			name: "multiple choice",
+			in:   "::Capital::What is the capital of France? {=Paris ~London ~Berlin}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Capital",
+				QuestionType:   canvas.MultipleChoice,
+				QuestionText:   "What is the capital of France?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{Text: "Paris", Weight: canvas.CorrectWeight},
+					{Text: "London"},
+					{Text: "Berlin"},
+				},
+			},
+		},
+		{
+			name: "multiple choice with feedback",
+			in:   "Pick one {=yes#good ~no#bad ####think about it}",
+			want: &canvas.QuizQuestion{
+				QuestionName:    "Question 1",
+				QuestionType:    canvas.MultipleChoice,
+				QuestionText:    "Pick one",
+				PointsPossible:  giftPoints,
+				NeutralComments: "think about it",
+				Answers: []*canvas.QuizAnswer{
+					{Text: "yes", Weight: canvas.CorrectWeight, Comments: "good"},
+					{Text: "no", Comments: "bad"},
+				},
+			},
+		},
+		{
+			name: "multiple answers",
+			in:   "Which are even? {~%50%2 ~%50%4 ~%-100%3}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   "multiple_answers_question",
+				QuestionText:   "Which are even?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{Text: "2", Weight: canvas.CorrectWeight},
+					{Text: "4", Weight: canvas.CorrectWeight},
+					{Text: "3"},
+				},
+			},
+		},
+		{
+			name: "true",
+			in:   "The sky is blue. {T}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.TrueFalse,
+				QuestionText:   "The sky is blue.",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{Text: "True", Weight: canvas.CorrectWeight},
+					{Text: "False"},
+				},
+			},
+		},
+		{
+			name: "false with feedback",
+			in:   "Pigs fly. {FALSE#they do not#right}",
+			want: &canvas.QuizQuestion{
+				QuestionName:      "Question 1",
+				QuestionType:      canvas.TrueFalse,
+				QuestionText:      "Pigs fly.",
+				PointsPossible:    giftPoints,
+				IncorrectComments: "they do not",
+				CorrectComments:   "right",
+				Answers: []*canvas.QuizAnswer{
+					{Text: "True"},
+					{Text: "False", Weight: canvas.CorrectWeight},
+				},
+			},
+		},
+		{
+			name: "short answer",
+			in:   "Two plus two is {=four =4}.",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.ShortAnswer,
+				QuestionText:   "Two plus two is _____ .",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{Text: "four", Weight: canvas.CorrectWeight},
+					{Text: "4", Weight: canvas.CorrectWeight},
+				},
+			},
+		},
+		{
+			name: "matching with a distractor",
+			in:   "Match them. {=cat -> meow =dog -> woof = -> moo}",
+			want: &canvas.QuizQuestion{
+				QuestionName:                   "Question 1",
+				QuestionType:                   canvas.Matching,
+				QuestionText:                   "Match them.",
+				PointsPossible:                 giftPoints,
+				MatchingAnswerIncorrectMatches: "moo",
+				Answers: []*canvas.QuizAnswer{
+					{Left: "cat", Right: "meow"},
+					{Left: "dog", Right: "woof"},
+				},
+			},
+		},
+		{
+			name: "numerical with a margin",
+			in:   "Pi? {#3.14:0.01}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.Numerical,
+				QuestionText:   "Pi?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{NumericalAnswerType: canvas.ExactAnswer, Exact: 3.14, Margin: 0.01, Weight: canvas.CorrectWeight},
+				},
+			},
+		},
+		{
+			name: "numerical range",
+			in:   "Between? {#1..5#close enough}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.Numerical,
+				QuestionText:   "Between?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{NumericalAnswerType: canvas.RangeAnswer, Start: 1, End: 5, Weight: canvas.CorrectWeight, Comments: "close enough"},
+				},
+			},
+		},
+		{
+			name: "numerical with several answers",
+			in:   "Year? {#=1969 =%50%1970:1}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.Numerical,
+				QuestionText:   "Year?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{NumericalAnswerType: canvas.ExactAnswer, Exact: 1969, Weight: canvas.CorrectWeight},
+					{NumericalAnswerType: canvas.ExactAnswer, Exact: 1970, Margin: 1, Weight: 50},
+				},
+			},
+		},
+		{
+			name: "essay",
+			in:   "[html]Tell me a story. {}",
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.Essay,
+				QuestionText:   "Tell me a story.",
+				PointsPossible: giftPoints,
+			},
+		},
+		{
+			name: "text only",
+			in:   "// a comment\nRead the following carefully.",
+			want: &canvas.QuizQuestion{
+				QuestionName: "Question 1",
+				QuestionType: "text_only_question",
+				QuestionText: "Read the following carefully.",
+			},
+		},
+		{
+			name: "escapes",
+			in:   `What is 1 \= 1 \{really\}\: \#1? {=yes \~ sure ~no}`,
+			want: &canvas.QuizQuestion{
+				QuestionName:   "Question 1",
+				QuestionType:   canvas.MultipleChoice,
+				QuestionText:   "What is 1 = 1 {really}: #1?",
+				PointsPossible: giftPoints,
+				Answers: []*canvas.QuizAnswer{
+					{Text: "yes ~ sure", Weight: canvas.CorrectWeight},
+					{Text: "no"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		questions, err := parseGIFT("test.gift", test.in)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(questions) != 1 {
+			t.Errorf("%s: got %d questions, expected 1", test.name, len(questions))
+			continue
+		}
+		if !reflect.DeepEqual(questions[0], test.want) {
+			t.Errorf("%s: got %s, expected %s", test.name, dumpQuestion(questions[0]), dumpQuestion(test.want))
+		}
+	}
+}
+
+func TestParseGIFTSplitsQuestions(t *testing.T) {
+	in := "$CATEGORY: quiz\n\n::One::First {T}\n\n// between\n\nSecond {\n=a\n\n~b\n}\n\n\nThird {F}\n"
+	questions, err := parseGIFT("test.gift", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, question := range questions {
+		names = append(names, question.QuestionName+": "+question.QuestionText)
+	}
+	want := []string{"One: First", "Question 2: Second", "Question 3: Third"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, expected %q", names, want)
+	}
+}
+
+func TestParseGIFTErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Question {=a ~b", "test.gift:1: answer block is not closed"},
+		{"First {T}\n\nSecond }", "test.gift:3: unexpected }"},
+		{"::Title\nno end {T}", "test.gift:1: question title is not closed with ::"},
+		{"{T}", "test.gift:1: question has no text"},
+		{"Pick\n{\n  a =b ~c}", "test.gift:3: expected an answer starting with = or ~"},
+		{"First {T}\n\nPick {=a =b ~c}", "test.gift:3: multiple choice answers need exactly one =right answer"},
+		{"Pick {~%x%a =b}", "test.gift:1: invalid answer weight \"x\""},
+		{"Pick {~%50a =b}", "test.gift:1: answer weight is not closed with %"},
+		{"Match\n{=a -> b\n~c -> d}", "test.gift:3: matching answers must look like =left -> right"},
+		{"Number\n\n\nSize? {#abc}", "test.gift:4: invalid numerical answer \"abc\""},
+		{"Size? {#=1\n~2}", "test.gift:2: numerical answers start with =, not ~"},
+	}
+	for _, test := range tests {
+		_, err := parseGIFT("test.gift", test.in)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.in)
+		} else if !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%q: got error %q, expected %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestParseAiken(t *testing.T) {
+	in := "What is 2+2?\nA. 3\nB. 4\nC) 5\nANSWER: B\n\nName the\nlargest planet\na) Mars\nb) Jupiter\nANSWER: b\n"
+	questions, err := parseAiken("test.txt", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*canvas.QuizQuestion{
+		{
+			QuestionName:   "Question 1",
+			QuestionType:   canvas.MultipleChoice,
+			QuestionText:   "What is 2+2?",
+			PointsPossible: giftPoints,
+			Answers: []*canvas.QuizAnswer{
+				{Text: "3"},
+				{Text: "4", Weight: canvas.CorrectWeight},
+				{Text: "5"},
+			},
+		},
+		{
+			QuestionName:   "Question 2",
+			QuestionType:   canvas.MultipleChoice,
+			QuestionText:   "Name the\nlargest planet",
+			PointsPossible: giftPoints,
+			Answers: []*canvas.QuizAnswer{
+				{Text: "Mars"},
+				{Text: "Jupiter", Weight: canvas.CorrectWeight},
+			},
+		},
+	}
+	if !reflect.DeepEqual(questions, want) {
+		var got []string
+		for _, question := range questions {
+			got = append(got, dumpQuestion(question))
+		}
+		t.Errorf("got %s", strings.Join(got, "\n"))
+	}
+}
+
+func TestParseAikenErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Question\nA. one\nANSWER: A", "test.txt:3: question needs at least two options"},
+		{"Question\nA. one\nB. two\nANSWER: C", "test.txt:4: answer C is not one of the options"},
+		{"Question\nA. one\nB. two\n\nANSWER: A", "test.txt:4: blank line before the ANSWER line of the question that starts on line 1"},
+		{"Question\nA. one\nB. two\nmore text\nANSWER: A", "test.txt:4: expected an option"},
+		{"Q1\nA. one\nB. two\nANSWER: A\n\nQ2\nA. one\nB. two", "test.txt:6: question has no ANSWER line"},
+	}
+	for _, test := range tests {
+		_, err := parseAiken("test.txt", test.in)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.in)
+		} else if !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%q: got error %q, expected %q", test.in, err, test.want)
+		}
+	}
+}
+
+func dumpQuestion(question *canvas.QuizQuestion) string {
+	var buf strings.Builder
+	if err := canvas.Encode(&buf, question, canvas.JSON); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
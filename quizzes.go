@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// otherQuestionTypes are question types that are passed through to Canvas without checks
+var otherQuestionTypes = map[string]bool{
+	"multiple_answers_question":        true,
+	"fill_in_multiple_blanks_question": true,
+	"multiple_dropdowns_question":      true,
+	"calculated_question":              true,
+	"file_upload_question":             true,
+	"text_only_question":               true,
+}
+
+// prepareQuiz checks the questions in a quiz entry and fills in the details
+// that can be inferred. Question types may leave off the "_question" suffix,
+// and answers to short answer and numerical questions are correct unless
+// they say otherwise.
+func prepareQuiz(quiz *canvas.Quiz) error {
+	if quiz.Title == "" {
+		return fmt.Errorf("quiz entry needs a title")
+	}
+	for i, question := range quiz.Questions {
+		if question.QuestionType != "" && !strings.HasSuffix(question.QuestionType, "_question") {
+			question.QuestionType += "_question"
+		}
+		if err := prepareQuestion(question); err != nil {
+			return fmt.Errorf("quiz %q question %d: %v", quiz.Title, i+1, err)
+		}
+	}
+	return nil
+}
+
+func prepareQuestion(question *canvas.QuizQuestion) error {
+	correct := 0
+	for _, answer := range question.Answers {
+		if answer.Weight == canvas.CorrectWeight {
+			correct++
+		}
+	}
+
+	switch question.QuestionType {
+	case canvas.MultipleChoice:
+		if len(question.Answers) < 2 {
+			return fmt.Errorf("multiple choice questions need at least two answers")
+		}
+		if correct != 1 {
+			return fmt.Errorf("multiple choice questions need exactly one answer with weight %d", canvas.CorrectWeight)
+		}
+	case canvas.TrueFalse:
+		if len(question.Answers) != 2 || correct != 1 {
+			return fmt.Errorf("true/false questions need two answers, one with weight %d", canvas.CorrectWeight)
+		}
+	case canvas.ShortAnswer:
+		if len(question.Answers) == 0 {
+			return fmt.Errorf("short answer questions need at least one accepted answer")
+		}
+		for _, answer := range question.Answers {
+			if answer.Weight == 0 {
+				answer.Weight = canvas.CorrectWeight
+			}
+		}
+	case canvas.Numerical:
+		if len(question.Answers) == 0 {
+			return fmt.Errorf("numerical questions need at least one answer")
+		}
+		for _, answer := range question.Answers {
+			if answer.Weight == 0 {
+				answer.Weight = canvas.CorrectWeight
+			}
+			switch answer.NumericalAnswerType {
+			case "":
+				answer.NumericalAnswerType = canvas.ExactAnswer
+			case canvas.ExactAnswer, canvas.RangeAnswer, canvas.PrecisionAnswer:
+			default:
+				return fmt.Errorf("unknown numerical_answer_type %q: expected %s, %s, or %s",
+					answer.NumericalAnswerType, canvas.ExactAnswer, canvas.RangeAnswer, canvas.PrecisionAnswer)
+			}
+		}
+	case canvas.Matching:
+		if len(question.Answers) == 0 {
+			return fmt.Errorf("matching questions need at least one pair")
+		}
+		for _, answer := range question.Answers {
+			if answer.Left == "" || answer.Right == "" {
+				return fmt.Errorf("matching question answers need a left and a right")
+			}
+		}
+	case canvas.Essay:
+		if len(question.Answers) > 0 {
+			return fmt.Errorf("essay questions do not have answers")
+		}
+	case "":
+		return fmt.Errorf("question needs a question_type")
+	default:
+		if !otherQuestionTypes[question.QuestionType] {
+			return fmt.Errorf("unknown question_type %q", question.QuestionType)
+		}
+	}
+	return nil
+}
+
+var fakeQuizID = 5000
+
+func uploadQuiz(ctx context.Context, client *canvas.Client, elt *canvas.Quiz, courseID int, dry bool) (int, error) {
+	if err := elt.Dump(os.Stdout); err != nil {
+		return 0, err
+	}
+	if dry {
+		if elt.ID == 0 {
+			fakeQuizID++
+			return fakeQuizID - 1, nil
+		}
+		return elt.ID, nil
+	}
+
+	quiz, err := client.SaveQuiz(ctx, courseID, elt)
+	if err != nil {
+		return 0, fmt.Errorf("uploading quiz %q: %v", elt.Title, err)
+	}
+
+	// a question list (even an empty one) replaces the existing questions
+	if elt.Questions != nil {
+		if err = client.SyncQuizQuestions(ctx, courseID, quiz.ID, elt.Questions); err != nil {
+			return quiz.ID, fmt.Errorf("uploading questions for quiz %q: %v", elt.Title, err)
+		}
+
+		// students do not see question changes until the quiz is published again
+		if elt.Published != nil && *elt.Published {
+			if err = client.PublishQuiz(ctx, courseID, quiz.ID); err != nil {
+				return quiz.ID, fmt.Errorf("publishing quiz %q: %v", elt.Title, err)
+			}
+		}
+	}
+	return quiz.ID, nil
+}
+
+// fetchQuiz fetches a quiz with its questions, ready to be written as a template entry
+func fetchQuiz(ctx context.Context, client *canvas.Client, courseID, quizID int) (*canvas.Quiz, error) {
+	quiz, err := client.GetQuiz(ctx, courseID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	if quiz.Questions, err = client.ListQuizQuestions(ctx, courseID, quizID); err != nil {
+		return nil, err
+	}
+	quiz.Cleanup()
+	return quiz, nil
+}
+
+// fetchGroupQuizzes fetches the quizzes behind the quiz assignments in a list of groups
+func fetchGroupQuizzes(ctx context.Context, client *canvas.Client, courseID int, groups []*canvas.AssignmentGroup) (map[int]*canvas.Quiz, error) {
+	quizzes := make(map[int]*canvas.Quiz)
+	for _, group := range groups {
+		for _, asst := range group.Assignments {
+			if asst.QuizID == 0 {
+				continue
+			}
+			quiz, err := fetchQuiz(ctx, client, courseID, asst.QuizID)
+			if err != nil {
+				return nil, fmt.Errorf("fetching quiz %q: %v", asst.Name, err)
+			}
+			quizzes[asst.QuizID] = quiz
+		}
+	}
+	return quizzes, nil
+}
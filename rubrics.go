@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// readRubrics reads a rubric library file, which is a template file that holds only rubric entries
+func readRubrics(filename string, format canvas.Format) ([]*canvas.LibraryRubric, error) {
+	entries, err := read(filename, format)
+	if err != nil {
+		return nil, err
+	}
+	var lst []*canvas.LibraryRubric
+	for i, aorg := range entries {
+		if aorg.Rubric == nil {
+			return nil, fmt.Errorf("%s: entry %d is not a rubric", filename, i+1)
+		}
+		lst = append(lst, aorg.Rubric)
+	}
+	return lst, nil
+}
+
+// rubricLibrary collects library rubrics by key, checking that each one is usable
+func rubricLibrary(lst []*canvas.LibraryRubric) (map[string]*canvas.LibraryRubric, error) {
+	library := make(map[string]*canvas.LibraryRubric)
+	for _, elt := range lst {
+		if elt.Key == "" {
+			return nil, fmt.Errorf("rubric %q needs a key so assignments can refer to it", elt.Title)
+		}
+		if _, present := library[elt.Key]; present {
+			return nil, fmt.Errorf("rubric key %q is used more than once", elt.Key)
+		}
+		if len(elt.Criteria) == 0 {
+			return nil, fmt.Errorf("rubric %q has no criteria", elt.Key)
+		}
+		if elt.Title == "" {
+			elt.Title = elt.Key
+		}
+		library[elt.Key] = elt
+	}
+	return library, nil
+}
+
+// resolveRubricRef links an assignment to the library rubric it names
+func resolveRubricRef(library map[string]*canvas.LibraryRubric, asst *canvas.Assignment) error {
+	if asst.RubricRef == "" {
+		return nil
+	}
+	if len(asst.Rubric) > 0 {
+		return fmt.Errorf("assignment %q has both a rubric and a rubric_ref", asst.Name)
+	}
+	lib, present := library[asst.RubricRef]
+	if !present {
+		return fmt.Errorf("assignment %q refers to unknown rubric %q", asst.Name, asst.RubricRef)
+	}
+	asst.Library = lib
+	return nil
+}
+
+var fakeRubricID = 3000
+
+// syncRubric saves a library rubric to the course's rubric bank. A rubric without an
+// ID is matched by title against the bank so that re-running does not create copies;
+// bank is filled in the first time it is needed.
+func syncRubric(ctx context.Context, client *canvas.Client, lib *canvas.LibraryRubric, courseID int, bank *[]*canvas.Rubric, dry bool) error {
+	if lib.ID == 0 && !dry {
+		if *bank == nil {
+			rubrics, err := client.ListRubrics(ctx, courseID)
+			if err != nil {
+				return fmt.Errorf("listing rubrics: %v", err)
+			}
+			*bank = append([]*canvas.Rubric{}, rubrics...)
+		}
+		for _, elt := range *bank {
+			if elt.Title == lib.Title {
+				lib.ID = elt.ID
+				break
+			}
+		}
+	}
+
+	log.Printf("uploading rubric %d (%s)", lib.ID, lib.Key)
+	if err := lib.Dump(os.Stdout); err != nil {
+		return err
+	}
+	if dry {
+		if lib.ID == 0 {
+			lib.ID = fakeRubricID
+			fakeRubricID++
+		}
+		return nil
+	}
+
+	id, err := client.SaveRubric(ctx, courseID, lib)
+	if err != nil {
+		return fmt.Errorf("uploading rubric %q: %v", lib.Key, err)
+	}
+	if lib.ID == 0 {
+		log.Printf("new rubric ID %d", id)
+	}
+	lib.ID = id
+	return nil
+}
+
+// associateRubric attaches an assignment's library rubric to it unless the
+// saved assignment already uses that rubric with the same settings
+func associateRubric(ctx context.Context, client *canvas.Client, elt, saved *canvas.Assignment, courseID int) error {
+	lib := elt.Library
+	if settings := saved.RubricSettings; settings != nil && settings.ID == lib.ID &&
+		settings.HideScoreTotal == lib.HideScoreTotal && saved.UseRubricForGrading == elt.UseRubricForGrading {
+		return nil
+	}
+	log.Printf("attaching rubric %d (%s) to assignment %d", lib.ID, lib.Key, saved.ID)
+	return client.AssociateRubric(ctx, courseID, &canvas.RubricAssociation{
+		RubricID:        lib.ID,
+		AssociationID:   saved.ID,
+		AssociationType: "Assignment",
+		UseForGrading:   elt.UseRubricForGrading,
+		HideScoreTotal:  lib.HideScoreTotal,
+		Purpose:         "grading",
+	})
+}
+
+var nonKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// rubricKey makes a library key out of a rubric title
+func rubricKey(title string) string {
+	key := strings.Trim(nonKeyChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if key == "" {
+		key = "rubric"
+	}
+	return key
+}
+
+// collapseRubrics finds rubrics that are used by more than one assignment and
+// moves them into library entries at the start of the list, replacing each
+// copy with a rubric_ref. Rubrics are identical if they match in everything
+// but their Canvas IDs.
+func collapseRubrics(entries []canvas.AssignmentOrGroup) ([]canvas.AssignmentOrGroup, error) {
+	// group the assignments by rubric content
+	var order []string
+	users := make(map[string][]*canvas.Assignment)
+	for _, aorg := range entries {
+		asst := aorg.Assignment
+		if asst == nil || len(asst.Rubric) == 0 {
+			continue
+		}
+		lib := libraryRubricFor(asst)
+		lib.ID = 0
+		sig, err := json.Marshal(lib)
+		if err != nil {
+			return nil, fmt.Errorf("JSON error encoding rubric: %v", err)
+		}
+		if _, present := users[string(sig)]; !present {
+			order = append(order, string(sig))
+		}
+		users[string(sig)] = append(users[string(sig)], asst)
+	}
+
+	var libs []canvas.AssignmentOrGroup
+	keys := make(map[string]bool)
+	for _, sig := range order {
+		assts := users[sig]
+		if len(assts) < 2 {
+			continue
+		}
+		lib := libraryRubricFor(assts[0])
+		key := rubricKey(lib.Title)
+		for n := 2; keys[key]; n++ {
+			key = fmt.Sprintf("%s-%d", rubricKey(lib.Title), n)
+		}
+		keys[key] = true
+		lib.Key = key
+		libs = append(libs, canvas.AssignmentOrGroup{Rubric: lib})
+
+		for _, asst := range assts {
+			asst.Rubric = nil
+			asst.RubricSettings = nil
+			asst.RubricRef = key
+		}
+	}
+	return append(libs, entries...), nil
+}
+
+// libraryRubricFor copies an assignment's rubric into a library rubric with
+// its criterion and rating IDs removed
+func libraryRubricFor(asst *canvas.Assignment) *canvas.LibraryRubric {
+	lib := new(canvas.LibraryRubric)
+	if settings := asst.RubricSettings; settings != nil {
+		lib.ID = settings.ID
+		lib.Title = settings.Title
+		lib.FreeFormCriterionComments = settings.FreeFormCriterionComments
+		lib.HideScoreTotal = settings.HideScoreTotal
+	}
+	for _, criterion := range asst.Rubric {
+		c := *criterion
+		c.ID = ""
+		c.Ratings = nil
+		for _, rating := range criterion.Ratings {
+			r := *rating
+			r.ID = ""
+			c.Ratings = append(c.Ratings, &r)
+		}
+		lib.Criteria = append(lib.Criteria, &c)
+	}
+	return lib
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// seriesNumber is replaced by the number of each assignment in a series
+const seriesNumber = "{{n}}"
+
+// checkSeries makes sure a series entry is complete.
+func checkSeries(series *canvas.Series) error {
+	if !strings.Contains(series.Name, seriesNumber) {
+		return fmt.Errorf("series %q: the name must include %s", series.Name, seriesNumber)
+	}
+	if series.Count <= 0 {
+		return fmt.Errorf("series %q: count must be positive", series.Name)
+	}
+	if series.Start != nil && series.Step == nil {
+		return fmt.Errorf("series %q: a start needs a step", series.Name)
+	}
+	if series.Assignment != nil && series.Assignment.Default {
+		return fmt.Errorf("series %q: the assignment cannot be a default", series.Name)
+	}
+	first := series.First
+	if first == 0 {
+		first = 1
+	}
+	inRange := func(n int) bool { return n >= first && n < first+series.Count }
+	for _, n := range series.Skip {
+		if !inRange(n) {
+			return fmt.Errorf("series %q: skip %d is not in the series", series.Name, n)
+		}
+	}
+	for n, item := range series.Items {
+		if !inRange(n) {
+			return fmt.Errorf("series %q: item %d is not in the series", series.Name, n)
+		}
+		if item == nil || item.Default {
+			return fmt.Errorf("series %q: item %d must be an assignment that is not a default", series.Name, n)
+		}
+	}
+	return nil
+}
+
+// expandSeries generates the assignments in a series, in order. Relative
+// times are resolved first, so the assignments are ready to be treated like
+// any other assignment entry.
+func expandSeries(cal *canvas.Calendar, series *canvas.Series) ([]*canvas.Assignment, error) {
+	if err := checkSeries(series); err != nil {
+		return nil, err
+	}
+	if err := resolveTimes(cal, "series", series.Name, series.Times()); err != nil {
+		return nil, err
+	}
+	shared := series.Assignment
+	if shared == nil {
+		shared = new(canvas.Assignment)
+	}
+
+	first := series.First
+	if first == 0 {
+		first = 1
+	}
+	var lst []*canvas.Assignment
+	for _, n := range series.Numbers() {
+		asst, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		// fields for this number replace the shared ones
+		if item := series.Items[n]; item != nil {
+			own, err := item.Clone()
+			if err != nil {
+				return nil, err
+			}
+			mergeDefault(own, asst)
+			asst = own
+		}
+
+		number := fmt.Sprint(n)
+		if asst.Name == "" {
+			asst.Name = series.Name
+		}
+		asst.Name = strings.Replace(asst.Name, seriesNumber, number, -1)
+		asst.Description = strings.Replace(asst.Description, seriesNumber, number, -1)
+		asst.DescriptionFile = strings.Replace(asst.DescriptionFile, seriesNumber, number, -1)
+
+		if asst.DueAt == nil && series.Start != nil {
+			asst.DueAt = &canvas.Time{Time: series.Step.After(series.Start.Time, n-first)}
+		}
+		if asst.ID == 0 {
+			asst.ID = series.IDs[n]
+		}
+		lst = append(lst, asst)
+	}
+	return lst, nil
+}
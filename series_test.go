@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestExpandSeries(t *testing.T) {
+	series := &canvas.Series{
+		Name:  "Lab {{n}}",
+		Count: 5,
+		First: 2,
+		Start: localTime(at(day(time.January, 12), 23, 59)),
+		Step:  &canvas.Step{Days: 7},
+		Skip:  []int{4},
+		Assignment: &canvas.Assignment{
+			PointsPossible:  10,
+			DescriptionFile: "labs/lab{{n}}.md",
+		},
+		Items: map[int]*canvas.Assignment{
+			5: {PointsPossible: 20},
+			6: {Name: "Final lab {{n}}", DueAt: localTime(at(day(time.February, 20), 17, 0))},
+		},
+		IDs: map[int]int{2: 102, 6: 106},
+	}
+	lst, err := expandSeries(nil, series)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		name   string
+		file   string
+		due    time.Time
+		points float64
+		id     int
+	}
+	want := []result{
+		{"Lab 2", "labs/lab2.md", at(day(time.January, 12), 23, 59), 10, 102},
+		{"Lab 3", "labs/lab3.md", at(day(time.January, 19), 23, 59), 10, 0},
+		// number 4 is skipped, but its week still counts
+		{"Lab 5", "labs/lab5.md", at(day(time.February, 2), 23, 59), 20, 0},
+		{"Final lab 6", "labs/lab6.md", at(day(time.February, 20), 17, 0), 10, 106},
+	}
+	if len(lst) != len(want) {
+		t.Fatalf("got %d assignments, expected %d", len(lst), len(want))
+	}
+	for i, asst := range lst {
+		got := result{asst.Name, asst.DescriptionFile, asst.DueAt.Time, asst.PointsPossible, asst.ID}
+		if got.name != want[i].name || got.file != want[i].file || !got.due.Equal(want[i].due) ||
+			got.points != want[i].points || got.id != want[i].id {
+			t.Errorf("assignment %d: got %+v, expected %+v", i, got, want[i])
+		}
+	}
+
+	// the shared assignment is copied, not changed
+	if series.Assignment.Name != "" || series.Assignment.DueAt != nil || series.Assignment.PointsPossible != 10 {
+		t.Errorf("the shared assignment was changed: %+v", series.Assignment)
+	}
+	if lst[0] == lst[1] || lst[0].DueAt == lst[1].DueAt {
+		t.Errorf("assignments share state")
+	}
+}
+
+func TestExpandSeriesRelativeStart(t *testing.T) {
+	cal := &canvas.Calendar{TermStart: localTime(day(time.January, 10))}
+	rel, err := canvas.ParseRelative("week 1 fri 23:59")
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := &canvas.Series{
+		Name:  "Quiz {{n}}",
+		Count: 3,
+		Start: &canvas.Time{Relative: rel},
+		Step:  &canvas.Step{Days: 14},
+	}
+	lst, err := expandSeries(cal, series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		at(day(time.January, 12), 23, 59),
+		at(day(time.January, 26), 23, 59),
+		at(day(time.February, 9), 23, 59),
+	}
+	for i, asst := range lst {
+		if !asst.DueAt.Equal(want[i]) {
+			t.Errorf("%s: got %s, expected %s", asst.Name, asst.DueAt.Format(time.RFC1123), want[i].Format(time.RFC1123))
+		}
+	}
+}
+
+func TestExpandSeriesErrors(t *testing.T) {
+	start := localTime(day(time.January, 12))
+	tests := []struct {
+		series canvas.Series
+		want   string
+	}{
+		{canvas.Series{Name: "Lab", Count: 3}, "the name must include {{n}}"},
+		{canvas.Series{Name: "Lab {{n}}"}, "count must be positive"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, Start: start}, "a start needs a step"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, Assignment: &canvas.Assignment{Default: true}}, "the assignment cannot be a default"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, Skip: []int{4}}, "skip 4 is not in the series"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, First: 2, Skip: []int{1}}, "skip 1 is not in the series"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, Items: map[int]*canvas.Assignment{0: {}}}, "item 0 is not in the series"},
+		{canvas.Series{Name: "Lab {{n}}", Count: 3, Items: map[int]*canvas.Assignment{2: nil}}, "item 2 must be an assignment that is not a default"},
+	}
+	for _, test := range tests {
+		_, err := expandSeries(nil, &test.series)
+		if err == nil {
+			t.Errorf("%+v: expected an error", test.series)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%+v: got error %q, expected it to mention %q", test.series, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// termWeekStart is the first day of a week when lining up terms
+const termWeekStart = time.Sunday
+
+// shiftDays returns how many days to move dates so that each one lands on the
+// same weekday of the same week of the new term. It is always a whole number of weeks.
+func shiftDays(oldStart, newStart time.Time) int {
+	oldWeek := weekStart(oldStart)
+	newWeek := weekStart(newStart)
+
+	// count calendar days rather than hours so daylight saving time does not interfere
+	oldDay := time.Date(oldWeek.Year(), oldWeek.Month(), oldWeek.Day(), 0, 0, 0, 0, time.UTC)
+	newDay := time.Date(newWeek.Year(), newWeek.Month(), newWeek.Day(), 0, 0, 0, 0, time.UTC)
+	return int(newDay.Sub(oldDay).Hours() / 24)
+}
+
+// weekStart returns the first day of the week containing t
+func weekStart(t time.Time) time.Time {
+	back := (int(t.Weekday()) - int(termWeekStart) + 7) % 7
+	return t.AddDate(0, 0, -back)
+}
+
+// shiftTime moves a timestamp by a number of days, keeping its local time of day.
+// Bare times of day (with no date) and class-relative dates are left alone.
+func shiftTime(t *canvas.Time, days int) {
+	if t.Relative != nil || t.IsTimeOfDay() {
+		return
+	}
+	*t = canvas.Time{Time: t.Local().AddDate(0, 0, days)}
+}
+
+// shiftFile moves every date in a template file (defaults included) from one term
+// to another and writes the result to w, optionally clearing course-specific IDs.
+// Include entries are kept as they are, so included files must be shifted on their own.
+func shiftFile(w io.Writer, filename string, format canvas.Format, oldStart, newStart string, clearIDs bool) error {
+	from, err := time.ParseInLocation("2006-01-02", oldStart, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid old term start date %q: %v", oldStart, err)
+	}
+	to, err := time.ParseInLocation("2006-01-02", newStart, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid new term start date %q: %v", newStart, err)
+	}
+
+	entries, err := readFile(filename, format)
+	if err != nil {
+		return err
+	}
+
+	days := shiftDays(from, to)
+	for i := range entries {
+		if entries[i].Include != "" {
+			log.Printf("%s includes %s, which is not shifted", filename, entries[i].Include)
+		}
+
+		// class-relative dates follow the term start
+		if cal := entries[i].Calendar; cal != nil && cal.TermStart != nil {
+			cal.TermStart = &canvas.Time{Time: to}
+		}
+		for _, t := range entries[i].Times() {
+			shiftTime(t, days)
+		}
+		if clearIDs {
+			entries[i].ClearIDs()
+		}
+	}
+
+	return canvas.Encode(w, entries, format)
+}
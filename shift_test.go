@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestShiftDays(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		{"same week", date(2024, time.January, 8), date(2024, time.January, 10), 0},
+		{"sunday starts a week", date(2024, time.January, 7), date(2024, time.January, 13), 0},
+		{"next sunday", date(2024, time.January, 7), date(2024, time.January, 14), 7},
+		{"saturday to sunday", date(2024, time.January, 13), date(2024, time.January, 14), 7},
+		{"one year later", date(2024, time.August, 26), date(2025, time.August, 25), 364},
+		{"weekday moves back", date(2024, time.January, 11), date(2025, time.January, 7), 364},
+		{"weekday moves forward", date(2024, time.January, 8), date(2025, time.January, 11), 364},
+		{"earlier term", date(2025, time.August, 25), date(2024, time.August, 26), -364},
+		{"leap day", date(2024, time.February, 26), date(2024, time.March, 4), 7},
+	}
+	for _, test := range tests {
+		if got := shiftDays(test.from, test.to); got != test.want {
+			t.Errorf("%s: shiftDays(%s, %s) = %d, expected %d", test.name,
+				test.from.Format("Mon 2006-01-02"), test.to.Format("Mon 2006-01-02"), got, test.want)
+		}
+	}
+}
+
+func TestShiftDaylightSaving(t *testing.T) {
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = denver
+
+	// daylight saving time starts on March 10, 2024, so the weeks are an hour short
+	from := time.Date(2024, time.March, 3, 0, 0, 0, 0, denver)
+	to := time.Date(2024, time.March, 17, 0, 0, 0, 0, denver)
+	if got := shiftDays(from, to); got != 14 {
+		t.Errorf("shiftDays across daylight saving time = %d, expected 14", got)
+	}
+
+	// the time of day stays the same on the clock
+	due := &canvas.Time{Time: time.Date(2024, time.March, 8, 23, 59, 0, 0, denver)}
+	shiftTime(due, 14)
+	if want := time.Date(2024, time.March, 22, 23, 59, 0, 0, denver); !due.Equal(want) {
+		t.Errorf("shiftTime across daylight saving time = %s, expected %s", due.Format(time.RFC1123), want.Format(time.RFC1123))
+	}
+}
+
+func TestShiftTimeSkips(t *testing.T) {
+	classTime := time.Date(0, time.January, 1, 10, 30, 0, 0, time.Local)
+	bare := &canvas.Time{Time: classTime}
+	shiftTime(bare, 7)
+	if !bare.Equal(classTime) {
+		t.Errorf("a bare time of day moved to %s", bare.Format(time.RFC1123))
+	}
+
+	rel, err := canvas.ParseRelative("week 3 fri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative := &canvas.Time{Relative: rel}
+	shiftTime(relative, 7)
+	if relative.Relative != rel || !relative.Time.IsZero() {
+		t.Errorf("a class-relative date was shifted to %s", relative.Format(time.RFC1123))
+	}
+}
@@ -1,144 +1,214 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
-)
+	"os"
 
-func upload(all []AssignmentOrGroup, courseID int, dry bool) {
-	standardJSON = true
+	"github.com/russross/canvasassignments/canvas"
+)
 
+func upload(ctx context.Context, client *canvas.Client, all []canvas.AssignmentOrGroup, courseID int, dry bool) error {
 	groupID := 0
+
+	// library rubrics are saved once, before the first assignment that uses them
+	synced := make(map[*canvas.LibraryRubric]bool)
+	var bank []*canvas.Rubric
+
+	// modules are saved last, once the assignments, quizzes, and discussions they list have IDs
+	names := make(contentNames)
+
+	// pages are matched by slug or title against the course, which is listed on first use
+	var pages []*canvas.Page
 	for _, aorg := range all {
-		if aorg.Group != nil {
+		if aorg.Module != nil {
+			continue
+		} else if aorg.Group != nil {
 			elt := aorg.Group
 			oldID := elt.ID
 			log.Printf("uploading group %d (%s)", elt.ID, elt.Name)
-			groupID = uploadGroup(elt, courseID, dry)
+			var err error
+			if groupID, err = uploadGroup(ctx, client, elt, courseID, dry); err != nil {
+				return err
+			}
 			if oldID == 0 {
 				log.Printf("new group ID %d", groupID)
+				if !dry {
+					elt.ID = groupID
+				}
 			}
 		} else if aorg.Assignment != nil {
 			elt := aorg.Assignment
 			if elt.Default {
-				log.Fatalf("upload found a default assignment")
+				return errors.New("upload found a default assignment")
 			}
 			if elt.AssignmentGroupID == 0 {
 				if groupID == 0 {
-					log.Fatalf("unable to determine group ID for assignment")
+					return errors.New("unable to determine group ID for assignment")
 				}
 				elt.AssignmentGroupID = groupID
 			} else if elt.AssignmentGroupID != groupID && groupID != 0 {
-				log.Fatalf("group ID mismatch for assignment: expected %d but found %d", groupID, elt.AssignmentGroupID)
+				return fmt.Errorf("group ID mismatch for assignment: expected %d but found %d", groupID, elt.AssignmentGroupID)
 			}
 			if elt.CourseID != courseID {
-				log.Fatalf("course ID mismatch for assignment: expected %d but found %d", courseID, elt.CourseID)
+				return fmt.Errorf("course ID mismatch for assignment: expected %d but found %d", courseID, elt.CourseID)
+			}
+			if elt.Library != nil && !synced[elt.Library] {
+				if err := syncRubric(ctx, client, elt.Library, courseID, &bank, dry); err != nil {
+					return err
+				}
+				synced[elt.Library] = true
 			}
 			oldID := elt.ID
 			log.Printf("uploading assignment %d (%s)", elt.ID, elt.Name)
-			newID := uploadAssignment(elt, courseID, dry)
-			if oldID == 0 {
+			newID, err := uploadAssignment(ctx, client, elt, courseID, dry)
+			if oldID == 0 && newID != 0 {
 				log.Printf("new assignment ID %d", newID)
+				if !dry {
+					elt.ID = newID
+				}
+			}
+			if err != nil {
+				return err
+			}
+			names.add(canvas.ItemAssignment, elt.Name, newID)
+			if elt.DiscussionTopic != nil {
+				topicID := elt.DiscussionTopic.ID
+				if dry {
+					fakeDiscussionID++
+					topicID = fakeDiscussionID - 1
+				}
+				names.add(canvas.ItemDiscussion, elt.Name, topicID)
+			}
+		} else if aorg.Quiz != nil {
+			elt := aorg.Quiz
+			if elt.AssignmentGroupID == 0 {
+				elt.AssignmentGroupID = groupID
+			} else if elt.AssignmentGroupID != groupID && groupID != 0 {
+				return fmt.Errorf("group ID mismatch for quiz: expected %d but found %d", groupID, elt.AssignmentGroupID)
+			}
+			oldID := elt.ID
+			log.Printf("uploading quiz %d (%s)", elt.ID, elt.Title)
+			newID, err := uploadQuiz(ctx, client, elt, courseID, dry)
+			if oldID == 0 && newID != 0 {
+				log.Printf("new quiz ID %d", newID)
+				if !dry {
+					elt.ID = newID
+				}
+			}
+			if err != nil {
+				return err
+			}
+			names.add(canvas.ItemQuiz, elt.Title, newID)
+		} else if aorg.Discussion != nil {
+			elt := aorg.Discussion
+			oldID := elt.ID
+			log.Printf("uploading discussion %d (%s)", elt.ID, elt.Title)
+			newID, err := uploadDiscussion(ctx, client, elt, courseID, dry)
+			if err != nil {
+				return err
+			}
+			if oldID == 0 {
+				log.Printf("new discussion ID %d", newID)
+				if !dry {
+					elt.ID = newID
+				}
+			}
+			names.add(canvas.ItemDiscussion, elt.Title, newID)
+		} else if aorg.Page != nil {
+			elt := aorg.Page
+			if pages == nil {
+				lst, err := client.ListPages(ctx, courseID)
+				if err != nil {
+					return fmt.Errorf("listing pages: %v", err)
+				}
+				pages = append([]*canvas.Page{}, lst...)
+			}
+			log.Printf("uploading page %q (%s)", elt.URL, elt.Title)
+			slug, err := uploadPage(ctx, client, elt, courseID, pages, dry)
+			if err != nil {
+				return err
+			}
+			if elt.URL != slug && !dry {
+				log.Printf("page %q has slug %q", elt.Title, slug)
+				elt.URL = slug
 			}
 		} else {
-			log.Fatalf("upload did not find a group or an assignment")
+			return errors.New("upload did not find a group, an assignment, a quiz, a discussion, a page, or a module")
 		}
 	}
+	return uploadModules(ctx, client, all, courseID, names, dry)
 }
 
 var fakeGroupID = 1000
 
-func uploadGroup(elt *AssignmentGroup, courseID int, dry bool) int {
-	Dump(elt)
+func uploadGroup(ctx context.Context, client *canvas.Client, elt *canvas.AssignmentGroup, courseID int, dry bool) (int, error) {
+	if err := canvas.Dump(os.Stdout, elt); err != nil {
+		return 0, err
+	}
 	if dry {
 		if elt.ID == 0 {
 			fakeGroupID++
-			return fakeGroupID - 1
+			return fakeGroupID - 1, nil
 		}
-		return elt.ID
+		return elt.ID, nil
 	}
 
-	raw, err := json.Marshal(elt)
+	group, err := client.SaveAssignmentGroup(ctx, courseID, elt)
 	if err != nil {
-		log.Fatalf("Error JSON encoding group: %v", err)
-	}
-	kind := "POST"
-	targetURL := fmt.Sprintf("%s/api/v1/courses/%d/assignment_groups", apiEndpoint, courseID)
-	if elt.ID != 0 {
-		kind = "PUT"
-		targetURL = fmt.Sprintf("%s/api/v1/courses/%d/assignment_groups/%d", apiEndpoint, courseID, elt.ID)
-	}
-	req, err := http.NewRequest(kind, targetURL, bytes.NewReader(raw))
-	if err != nil {
-		log.Fatalf("error creating http request in uploadGroup: %v", err)
-	}
-	req.Header.Add("Authorization", authHeader)
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatalf("POST error in uploadGroup: %v", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Fatalf("POST response in uploadGroup %d: %s", resp.StatusCode, resp.Status)
+		return 0, fmt.Errorf("uploading group %q: %v", elt.Name, err)
 	}
-
-	// decode response
-	decoder := json.NewDecoder(resp.Body)
-	elt = new(AssignmentGroup)
-	if err = decoder.Decode(elt); err != nil {
-		log.Fatalf("Error decoding object: %v", err)
-	}
-	return elt.ID
+	return group.ID, nil
 }
 
 var fakeAsstID = 2000
 
-func uploadAssignment(elt *Assignment, courseID int, dry bool) int {
-	Dump(elt)
+func uploadAssignment(ctx context.Context, client *canvas.Client, elt *canvas.Assignment, courseID int, dry bool) (int, error) {
+	if err := canvas.Dump(os.Stdout, elt); err != nil {
+		return 0, err
+	}
 	if dry {
 		if elt.ID == 0 {
 			fakeAsstID++
-			return fakeAsstID - 1
+			return fakeAsstID - 1, nil
 		}
-		return elt.ID
+		return elt.ID, nil
 	}
 
-	raw, err := json.Marshal(&AssignmentOrGroup{Assignment: elt})
-	if err != nil {
-		log.Fatalf("Error JSON encoding assignment: %v", err)
-	}
-	kind := "POST"
-	targetURL := fmt.Sprintf("%s/api/v1/courses/%d/assignments", apiEndpoint, courseID)
-	if elt.ID != 0 {
-		kind = "PUT"
-		targetURL = fmt.Sprintf("%s/api/v1/courses/%d/assignments/%d", apiEndpoint, courseID, elt.ID)
-	}
-	req, err := http.NewRequest(kind, targetURL, bytes.NewReader(raw))
+	asst, err := client.SaveAssignment(ctx, courseID, elt)
 	if err != nil {
-		log.Fatalf("error creating http request in uploadAssignment: %v", err)
+		return 0, fmt.Errorf("uploading assignment %q: %v", elt.Name, err)
 	}
-	req.Header.Add("Authorization", authHeader)
-	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatalf("POST error in uploadAssignment: %v", err)
+	// an overrides list (even an empty one) replaces the existing overrides
+	if elt.Overrides != nil {
+		if err = client.SyncOverrides(ctx, courseID, asst.ID, elt.Overrides); err != nil {
+			return asst.ID, fmt.Errorf("uploading overrides for assignment %q: %v", elt.Name, err)
+		}
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Fatalf("POST response in uploadAssignment %d: %s", resp.StatusCode, resp.Status)
+
+	// discussion settings are saved on the topic that Canvas creates for the assignment
+	if elt.DiscussionTopic != nil {
+		if err = uploadGradedDiscussion(ctx, client, elt, asst, courseID); err != nil {
+			return asst.ID, err
+		}
 	}
 
-	// decode response
-	decoder := json.NewDecoder(resp.Body)
-	elt = new(Assignment)
-	if err = decoder.Decode(elt); err != nil {
-		log.Fatalf("Error decoding object: %v", err)
+	// rubrics go through their own API and are attached to the assignment afterward
+	if elt.Library != nil {
+		if err = associateRubric(ctx, client, elt, asst, courseID); err != nil {
+			return asst.ID, fmt.Errorf("attaching rubric %q to assignment %q: %v", elt.Library.Key, elt.Name, err)
+		}
+	} else if len(elt.Rubric) > 0 {
+		saved := *elt
+		saved.ID = asst.ID
+		rubricID, err := client.SaveAssignmentRubric(ctx, courseID, &saved)
+		if err != nil {
+			return asst.ID, fmt.Errorf("uploading rubric for assignment %q: %v", elt.Name, err)
+		}
+		log.Printf("rubric ID %d attached to assignment %d", rubricID, asst.ID)
 	}
-	return elt.ID
+	return asst.ID, nil
 }
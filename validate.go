@@ -0,0 +1,620 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/russross/canvasassignments/canvas"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// problem is something wrong with a template file, and where it was found
+type problem struct {
+	file      string
+	line, col int
+	msg       string
+}
+
+func (p problem) Error() string {
+	if p.line == 0 {
+		return fmt.Sprintf("%s: %s", p.file, p.msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.file, p.line, p.col, p.msg)
+}
+
+// problemList is the error returned when validation finds problems
+type problemList []problem
+
+func (lst problemList) Error() string {
+	var lines []string
+	for _, p := range lst {
+		lines = append(lines, p.Error())
+	}
+	if len(lst) == 1 {
+		return lines[0]
+	}
+	return fmt.Sprintf("%d problems found:\n\t%s", len(lst), strings.Join(lines, "\n\t"))
+}
+
+// enumFields are the fields that only accept certain values, keyed by
+// the name of the type that holds them and their key
+var enumFields = map[string][]string{
+	"Assignment.submission_types":                    canvas.SubmissionTypes,
+	"Assignment.grading_type":                        canvas.GradingTypes,
+	"TurnitinSettings.originality_report_visibility": canvas.OriginalityReportVisibilities,
+	"DiscussionTopic.discussion_type":                {canvas.DiscussionSideComment, canvas.DiscussionThreaded},
+	"Calendar.policy":                                {canvas.PolicyForward, canvas.PolicyBackward, canvas.PolicyWarn},
+	"ModuleItem.type": {
+		canvas.ItemAssignment, canvas.ItemQuiz, canvas.ItemDiscussion, canvas.ItemPage,
+		canvas.ItemFile, canvas.ItemSubHeader, canvas.ItemExternal, canvas.ItemTool,
+	},
+	"CompletionRequirement.type": {
+		canvas.RequireView, canvas.RequireSubmit, canvas.RequireContribute, canvas.RequireMinScore, canvas.RequireMarkDone,
+	},
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// validateTemplate checks a template file and the files it includes for
+// syntax errors, unknown fields, values of the wrong type, and values that
+// Canvas does not accept. It does not check how entries fit together, which
+// applyDefaults does.
+func validateTemplate(filename string, format canvas.Format) error {
+	v := &validator{seen: make(map[string]bool)}
+	v.file(filename, format, reflect.TypeOf([]canvas.AssignmentOrGroup{}), "entry")
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+	return nil
+}
+
+// validateFile checks a single file that holds a value of type t, described by name.
+func validateFile(filename string, format canvas.Format, t reflect.Type, name string) error {
+	v := &validator{seen: make(map[string]bool)}
+	v.file(filename, format, t, name)
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+	return nil
+}
+
+type validator struct {
+	filename string
+	format   canvas.Format
+	seen     map[string]bool
+	problems problemList
+}
+
+func (v *validator) add(n *node, format string, args ...interface{}) {
+	p := problem{file: v.filename, msg: fmt.Sprintf(format, args...)}
+	if n != nil {
+		p.line, p.col = n.line, n.col
+	}
+	v.problems = append(v.problems, p)
+}
+
+// file checks one file that holds a value of type t, described by name.
+// Included files are checked once each; read reports include cycles.
+func (v *validator) file(filename string, format canvas.Format, t reflect.Type, name string) {
+	if abs, err := filepath.Abs(filename); err == nil {
+		if v.seen[abs] {
+			return
+		}
+		v.seen[abs] = true
+	}
+	// anything but YAML decodes as JSON
+	if format != canvas.YAML {
+		format = canvas.JSON
+	}
+	outerFile, outerFormat := v.filename, v.format
+	v.filename, v.format = filename, format
+	defer func() { v.filename, v.format = outerFile, outerFormat }()
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		v.add(nil, "%v", err)
+		return
+	}
+	var root *node
+	if format == canvas.YAML {
+		root, err = parseYAML(contents)
+	} else {
+		root, err = parseJSON(contents)
+	}
+	if err != nil {
+		if p, ok := err.(problem); ok {
+			p.file = filename
+			v.problems = append(v.problems, p)
+		} else {
+			v.add(nil, "%v", err)
+		}
+		return
+	}
+	if root == nil {
+		return
+	}
+	if t != reflect.TypeOf([]canvas.AssignmentOrGroup{}) {
+		v.check(root, t, name)
+		return
+	}
+	if root.kind != arrayNode {
+		v.add(root, "expected a list of entries, found %s", root.describe(format))
+		return
+	}
+	v.check(root, t, "entry")
+
+	// check the shape of each entry, and the files it includes
+	for _, entry := range root.items {
+		v.entry(entry)
+	}
+}
+
+// entry checks that a template entry is exactly one kind of thing, and
+// checks the file that it includes, if any.
+func (v *validator) entry(n *node) {
+	if n.kind != objectNode {
+		return
+	}
+	if len(n.keys) == 0 {
+		v.add(n, "empty entry")
+		return
+	}
+	if len(n.keys) > 1 {
+		v.add(n.keys[1], "entry has both %q and %q; each entry should be a single kind", n.keys[0].text, n.keys[1].text)
+	}
+	if n.keys[0].text == "include" && n.items[0].kind == stringNode {
+		included := includePath(v.filename, n.items[0].text)
+		v.file(included, canvas.FormatFor(included), reflect.TypeOf([]canvas.AssignmentOrGroup{}), "entry")
+	}
+}
+
+// check checks that a node can be decoded into a value of type t.
+// name describes where the value is for messages.
+func (v *validator) check(n *node, t reflect.Type, name string) {
+	// null leaves any value at zero
+	if n.kind == nullNode {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return
+	}
+
+	// types that decode themselves from a string, like times and durations
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		if n.kind == objectNode || n.kind == arrayNode {
+			v.add(n, "%s: expected a string, found %s", name, n.describe(v.format))
+			return
+		}
+		raw := []byte(n.text)
+		if n.kind == stringNode || v.format == canvas.YAML {
+			raw, _ = json.Marshal(n.text)
+		}
+		if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+			v.add(n, "%s: %v", name, err)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.kind != objectNode {
+			v.add(n, "%s: expected %s, found %s", name, v.objectName(), n.describe(v.format))
+			return
+		}
+		v.fields(n, t, name)
+
+	case reflect.Map:
+		if n.kind != objectNode {
+			v.add(n, "%s: expected %s, found %s", name, v.objectName(), n.describe(v.format))
+			return
+		}
+		for i, key := range n.keys {
+			switch t.Key().Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if _, err := strconv.Atoi(key.text); err != nil {
+					v.add(key, "%s: key %q is not a whole number", name, key.text)
+					continue
+				}
+			}
+			v.check(n.items[i], t.Elem(), name+"."+key.text)
+		}
+
+	case reflect.Slice:
+		if n.kind != arrayNode {
+			v.add(n, "%s: expected a list, found %s", name, n.describe(v.format))
+			return
+		}
+		for _, item := range n.items {
+			v.check(item, t.Elem(), name)
+		}
+
+	case reflect.String:
+		// YAML turns any scalar into a string
+		if n.kind != stringNode && (v.format != canvas.YAML || n.kind == objectNode || n.kind == arrayNode) {
+			v.add(n, "%s: expected a string, found %s", name, n.describe(v.format))
+		}
+
+	case reflect.Bool:
+		if n.kind != boolNode && !(v.format == canvas.YAML && n.kind == stringNode && yamlBool.MatchString(n.text)) {
+			v.add(n, "%s: expected true or false, found %s", name, n.describe(v.format))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.kind != numberNode {
+			v.add(n, "%s: expected a whole number, found %s", name, n.describe(v.format))
+		} else if _, err := strconv.ParseInt(n.text, 0, 64); err != nil {
+			v.add(n, "%s: expected a whole number, found %s", name, n.text)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if n.kind != numberNode {
+			v.add(n, "%s: expected a number, found %s", name, n.describe(v.format))
+		}
+	}
+}
+
+// yamlBool matches the words other than true and false that YAML 1.1 reads as booleans
+var yamlBool = regexp.MustCompile(`^(?i:y|yes|n|no|on|off)$`)
+
+// fields checks the fields of an object against struct type t.
+func (v *validator) fields(n *node, t reflect.Type, name string) {
+	fields := structFields(t, v.format)
+	for i, key := range n.keys {
+		field, present := fields[key.text]
+		if !present {
+			msg := fmt.Sprintf("unknown field %q in %s", key.text, name)
+			if guess := suggest(key.text, fields); guess != "" {
+				msg += fmt.Sprintf("; did you mean %q?", guess)
+			}
+			v.add(key, "%s", msg)
+			continue
+		}
+		value := n.items[i]
+		v.check(value, field.Type, key.text)
+		if allowed := enumFields[t.Name()+"."+key.text]; allowed != nil {
+			v.enum(value, key.text, allowed)
+		}
+	}
+}
+
+// enum checks that a string, or each string in a list, is one of the allowed values.
+func (v *validator) enum(n *node, name string, allowed []string) {
+	values := []*node{n}
+	if n.kind == arrayNode {
+		values = n.items
+	}
+	for _, elt := range values {
+		if elt.kind != stringNode {
+			continue
+		}
+		found := false
+		for _, s := range allowed {
+			if elt.text == s {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		choices := make(map[string]reflect.StructField)
+		for _, s := range allowed {
+			choices[s] = reflect.StructField{}
+		}
+		msg := fmt.Sprintf("%s: unknown value %q", name, elt.text)
+		if guess := suggest(elt.text, choices); guess != "" {
+			msg += fmt.Sprintf("; did you mean %q?", guess)
+		} else {
+			msg += fmt.Sprintf("; expected one of %s", strings.Join(allowed, ", "))
+		}
+		v.add(elt, "%s", msg)
+	}
+}
+
+func (v *validator) objectName() string {
+	if v.format == canvas.YAML {
+		return "a mapping"
+	}
+	return "an object"
+}
+
+// structFields maps the keys of a struct type in the given format to its fields.
+func structFields(t reflect.Type, format canvas.Format) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get(string(format))
+		parts := strings.Split(tag, ",")
+		key := parts[0]
+		if key == "-" {
+			continue
+		}
+		inline := false
+		for _, opt := range parts[1:] {
+			inline = inline || opt == "inline"
+		}
+		if (field.Anonymous && tag == "") || inline {
+			for k, f := range structFields(field.Type, format) {
+				fields[k] = f
+			}
+			continue
+		}
+		if key == "" {
+			key = field.Name
+			if format == canvas.YAML {
+				key = strings.ToLower(key)
+			}
+		}
+		fields[key] = field
+	}
+	return fields
+}
+
+// suggest returns the known key that is closest to an unknown one, if
+// any is close enough to be a likely typo.
+func suggest(key string, known map[string]reflect.StructField) string {
+	best, bestDistance := "", 0
+	for candidate := range known {
+		d := editDistance(strings.ToLower(key), strings.ToLower(candidate))
+		if best == "" || d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	limit := 2
+	if n := utf8.RuneCountInString(key) / 3; n > limit {
+		limit = n
+	}
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(t)]
+}
+
+// node is a parsed JSON or YAML value along with where it starts in the file
+type node struct {
+	kind      nodeKind
+	line, col int
+
+	// text is the value of a scalar
+	text string
+
+	// keys and items hold the keys and values of an object, or the items of a list
+	keys  []*node
+	items []*node
+}
+
+type nodeKind int
+
+const (
+	nullNode nodeKind = iota
+	boolNode
+	numberNode
+	stringNode
+	arrayNode
+	objectNode
+)
+
+func (n *node) describe(format canvas.Format) string {
+	switch n.kind {
+	case nullNode:
+		return "null"
+	case boolNode, numberNode:
+		return n.text
+	case stringNode:
+		return strconv.Quote(n.text)
+	case arrayNode:
+		return "a list"
+	}
+	if format == canvas.YAML {
+		return "a mapping"
+	}
+	return "an object"
+}
+
+// parseJSON parses a JSON document into nodes. A syntax error is returned as a problem.
+func parseJSON(data []byte) (*node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	n, err := parseJSONValue(decoder, data)
+	if err == nil {
+		end := int(decoder.InputOffset())
+		end += len(data[end:]) - len(bytes.TrimLeft(data[end:], " \t\r\n"))
+		if _, err = decoder.Token(); err == io.EOF {
+			return n, nil
+		} else if err == nil {
+			line, col := position(data, end)
+			return nil, problem{line: line, col: col, msg: "unexpected data after the end of the document"}
+		}
+	}
+	offset := int(decoder.InputOffset())
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err, offset = io.ErrUnexpectedEOF, len(data)
+	}
+	if syntax, ok := err.(*json.SyntaxError); ok && syntax.Offset > 0 {
+		// the offset is just past the character that caused the error
+		offset = int(syntax.Offset) - 1
+	}
+	line, col := position(data, offset)
+	return nil, problem{line: line, col: col, msg: err.Error()}
+}
+
+func parseJSONValue(decoder *json.Decoder, data []byte) (*node, error) {
+	start := skipSeparators(data, int(decoder.InputOffset()))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := new(node)
+	n.line, n.col = position(data, start)
+	switch elt := token.(type) {
+	case json.Delim:
+		if elt == '[' {
+			n.kind = arrayNode
+			for decoder.More() {
+				item, err := parseJSONValue(decoder, data)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		} else {
+			n.kind = objectNode
+			for decoder.More() {
+				key, err := parseJSONValue(decoder, data)
+				if err != nil {
+					return nil, err
+				}
+				value, err := parseJSONValue(decoder, data)
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key)
+				n.items = append(n.items, value)
+			}
+		}
+		// the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind, n.text = stringNode, elt
+	case json.Number:
+		n.kind, n.text = numberNode, elt.String()
+	case bool:
+		n.kind, n.text = boolNode, strconv.FormatBool(elt)
+	case nil:
+		n.kind = nullNode
+	}
+	return n, nil
+}
+
+// skipSeparators skips the white space, commas, and colons before a JSON token
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// position finds the line and column of a byte offset
+func position(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseYAML parses a YAML document into nodes. A syntax error is returned as a problem.
+func parseYAML(data []byte) (*node, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, problem{line: line, col: 1, msg: m[2]}
+		}
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return convertYAML(doc.Content[0], 0), nil
+}
+
+func convertYAML(y *yaml3.Node, depth int) *node {
+	n := &node{line: y.Line, col: y.Column}
+	if depth > 100 {
+		// a recursive alias
+		return n
+	}
+	switch y.Kind {
+	case yaml3.AliasNode:
+		alias := convertYAML(y.Alias, depth+1)
+		alias.line, alias.col = y.Line, y.Column
+		return alias
+	case yaml3.SequenceNode:
+		n.kind = arrayNode
+		for _, item := range y.Content {
+			n.items = append(n.items, convertYAML(item, depth+1))
+		}
+	case yaml3.MappingNode:
+		n.kind = objectNode
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			key, value := y.Content[i], convertYAML(y.Content[i+1], depth+1)
+			if key.ShortTag() == "!!merge" {
+				// the keys of merged mappings count as keys of this one
+				merged := []*node{value}
+				if value.kind == arrayNode {
+					merged = value.items
+				}
+				for _, m := range merged {
+					n.keys = append(n.keys, m.keys...)
+					n.items = append(n.items, m.items...)
+				}
+				continue
+			}
+			n.keys = append(n.keys, convertYAML(key, depth+1))
+			n.items = append(n.items, value)
+		}
+	case yaml3.ScalarNode:
+		n.text = y.Value
+		switch y.ShortTag() {
+		case "!!null":
+			n.kind = nullNode
+		case "!!bool":
+			n.kind = boolNode
+		case "!!int", "!!float":
+			n.kind = numberNode
+		default:
+			n.kind = stringNode
+		}
+	}
+	return n
+}
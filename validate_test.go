@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+func TestSuggest(t *testing.T) {
+	fields := structFields(reflect.TypeOf(canvas.Assignment{}), canvas.JSON)
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"poinst_possible", "points_possible"},
+		{"points_posible", "points_possible"},
+		{"Due_At", "due_at"},
+		{"dueat", "due_at"},
+		{"submission_type", "submission_types"},
+		{"descripton_file", "description_file"},
+		{"nmae", "name"},
+		{"colour", ""},
+		{"deadline", ""},
+	}
+	for _, test := range tests {
+		if got := suggest(test.key, fields); got != test.want {
+			t.Errorf("suggest(%q) = %q, expected %q", test.key, got, test.want)
+		}
+	}
+	if got := suggest("name", nil); got != "" {
+		t.Errorf("suggest with no known keys = %q, expected nothing", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"due_at", "dueat", 1},
+		{"poinst", "points", 2},
+		{"café", "cafe", 1},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestParseJSONPositions(t *testing.T) {
+	data := "[\n  {\"assignment\": {\n    \"name\": \"Lab ü\", \"points_possible\": 10,\n\t\"published\": null}}\n]"
+	root, err := parseJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := root.items[0]
+	asst := entry.items[0]
+	tests := []struct {
+		what      string
+		n         *node
+		line, col int
+	}{
+		{"root", root, 1, 1},
+		{"entry", entry, 2, 3},
+		{"assignment key", entry.keys[0], 2, 4},
+		{"assignment", asst, 2, 18},
+		{"name key", asst.keys[0], 3, 5},
+		{"name", asst.items[0], 3, 13},
+		{"points key", asst.keys[1], 3, 22},
+		{"points", asst.items[1], 3, 41},
+		{"published", asst.items[2], 4, 15},
+	}
+	for _, test := range tests {
+		if test.n.line != test.line || test.n.col != test.col {
+			t.Errorf("%s: got %d:%d, expected %d:%d", test.what, test.n.line, test.n.col, test.line, test.col)
+		}
+	}
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"[\n  {\"assignment\": {\"name\": \"x\",}}\n]", "2:30: invalid character ',' looking for beginning of value"},
+		{"[\n  {\"name\": 1 \"x\"}\n]", "2:14: invalid character '\"' after object key:value pair"},
+		{"[1] [2]", "1:5: unexpected data after the end of the document"},
+		{"[\n  {\"name\": ", "2:12: unexpected EOF"},
+		{"[\n  {\"name\": tru}\n]", "2:15: invalid character '}' in literal true (expecting 'e')"},
+	}
+	for _, test := range tests {
+		_, err := parseJSON([]byte(test.in))
+		p, ok := err.(problem)
+		if !ok {
+			t.Errorf("%q: got %v, expected a problem", test.in, err)
+			continue
+		}
+		p.file = "t.json"
+		if got := p.Error(); got != "t.json:"+test.want {
+			t.Errorf("%q: got %q, expected %q", test.in, got, "t.json:"+test.want)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, contents string) string {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	good := write("good.yaml", `
+- assignment_group: {name: Labs, group_weight: 10}
+- assignment: &base
+    default: true
+    submission_types: [online_upload]
+    published: yes
+- assignment:
+    <<: *base
+    default: false
+    name: Lab 1
+    due_at: 2024-01-12 23:59
+- include: good.json
+`)
+	write("good.json", `[
+  {"discussion": {"title": "Intro", "discussion_type": "threaded"}}
+]`)
+	if err := validateTemplate(good, canvas.YAML); err != nil {
+		t.Errorf("good.yaml: %v", err)
+	}
+
+	bad := write("bad.yaml", `
+- assignment_group: {name: Labs, group_weight: heavy}
+- assignment:
+    name: Lab 1
+    poinst_possible: 10
+    submission_types: [online_uplaod, paper]
+    grading_type: point
+    due_at: next tuesday
+- assignmnet: {name: x}
+- {assignment: {name: y}, quiz: {title: z}}
+- include: bad.json
+`)
+	write("bad.json", `[
+  {"assignment": {"name": "J", "points_possible": "10"}},
+  {"page": {"title": "P", "publishd": true}}
+]`)
+	err = validateTemplate(bad, canvas.YAML)
+	lst, ok := err.(problemList)
+	if !ok {
+		t.Fatalf("got %v, expected a list of problems", err)
+	}
+	var got []string
+	for _, p := range lst {
+		got = append(got, strings.TrimPrefix(p.Error(), dir+string(filepath.Separator)))
+	}
+	want := []string{
+		`bad.yaml:2:48: group_weight: expected a number, found "heavy"`,
+		`bad.yaml:5:5: unknown field "poinst_possible" in assignment; did you mean "points_possible"?`,
+		`bad.yaml:6:24: submission_types: unknown value "online_uplaod"; did you mean "online_upload"?`,
+		`bad.yaml:6:39: submission_types: unknown value "paper"; expected one of none, on_paper,`,
+		`bad.yaml:7:19: grading_type: unknown value "point"; did you mean "points"?`,
+		`bad.yaml:8:13: due_at: parsing time "next tuesday"`,
+		`bad.yaml:9:3: unknown field "assignmnet" in entry; did you mean "assignment"?`,
+		`bad.yaml:10:27: entry has both "assignment" and "quiz"; each entry should be a single kind`,
+		`bad.json:2:51: points_possible: expected a number, found "10"`,
+		`bad.json:3:27: unknown field "publishd" in page; did you mean "published"?`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got problems:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("got %q, expected %q", got[i], want[i])
+		}
+	}
+}
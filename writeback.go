@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/russross/canvasassignments/canvas"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// writeIDs copies the IDs of newly created groups, assignments, quizzes,
+// discussions, and modules, and the slugs of new pages, from the uploaded
+// entries into the template file. The IDs of assignments generated by a series
+// are recorded in the series by number, and the IDs of entries from an
+// included file are written to that file. A YAML file is edited in place, so
+// its comments, anchors, and layout are kept, though it may be reindented. A
+// JSON file is written out again from its entries, which keeps default
+// entries, relative dates, and entry order but not formatting.
+// It returns the number of entries that were updated.
+func writeIDs(filename string, format canvas.Format, entries []canvas.AssignmentOrGroup) (int, error) {
+	// check that the files line up with the uploaded entries before changing any of them
+	_, used, err := writeFileIDs(filename, format, entries, false)
+	if err != nil {
+		return 0, err
+	}
+	if used != len(entries) {
+		return 0, fmt.Errorf("%s has fewer entries than were uploaded", filename)
+	}
+	changed, _, err := writeFileIDs(filename, format, entries, true)
+	return changed, err
+}
+
+// writeFileIDs records IDs in one template file and the files it includes,
+// starting from the first of the uploaded entries. It returns the number of
+// entries updated and the number of uploaded entries that the files account for.
+// Unless write is set, it only counts them and leaves the files alone.
+func writeFileIDs(filename string, format canvas.Format, entries []canvas.AssignmentOrGroup, write bool) (int, int, error) {
+	// applyDefaults merges into the entries it is given, so start over from the file
+	original, err := readFile(filename, format)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// every group, quiz, discussion, page, module, and non-default assignment in the file produced exactly one uploaded entry
+	changed, included, i := 0, 0, 0
+	var edits []idEdit
+	for k, aorg := range original {
+		if aorg.Include != "" {
+			name := includePath(filename, aorg.Include)
+			n, used, err := writeFileIDs(name, canvas.FormatFor(name), entries[i:], write)
+			if err != nil {
+				return 0, 0, err
+			}
+			included += n
+			i += used
+			continue
+		}
+		if aorg.Calendar != nil || aorg.Rubric != nil || (aorg.Assignment != nil && aorg.Assignment.Default) {
+			continue
+		}
+		if aorg.Series != nil {
+			// a series produced one assignment for each of its numbers
+			series := aorg.Series
+			before := changed
+			for _, n := range series.Numbers() {
+				if i >= len(entries) || entries[i].Assignment == nil {
+					return 0, 0, fmt.Errorf("series %q in %s does not match the uploaded entries", series.Name, filename)
+				}
+				uploaded := entries[i].Assignment
+				i++
+				if item := series.Items[n]; (item != nil && item.ID != 0) || series.IDs[n] != 0 || uploaded.ID == 0 {
+					continue
+				}
+				if series.IDs == nil {
+					series.IDs = make(map[int]int)
+				}
+				series.IDs[n] = uploaded.ID
+				changed++
+			}
+			if changed > before {
+				edits = append(edits, idEdit{k, "series", "ids", series.IDs})
+			}
+			continue
+		}
+		if i >= len(entries) {
+			return 0, 0, fmt.Errorf("%s has more entries than were uploaded", filename)
+		}
+		uploaded := entries[i]
+		i++
+
+		switch {
+		case aorg.Group != nil && uploaded.Group != nil:
+			if aorg.Group.ID == 0 && uploaded.Group.ID != 0 {
+				aorg.Group.ID = uploaded.Group.ID
+				edits = append(edits, idEdit{k, "assignment_group", "id", aorg.Group.ID})
+				changed++
+			}
+		case aorg.Assignment != nil && uploaded.Assignment != nil:
+			if aorg.Assignment.ID == 0 && uploaded.Assignment.ID != 0 {
+				aorg.Assignment.ID = uploaded.Assignment.ID
+				aorg.Assignment.AssignmentGroupID = uploaded.Assignment.AssignmentGroupID
+				edits = append(edits, idEdit{k, "assignment", "id", aorg.Assignment.ID})
+				if aorg.Assignment.AssignmentGroupID != 0 {
+					edits = append(edits, idEdit{k, "assignment", "assignment_group_id", aorg.Assignment.AssignmentGroupID})
+				}
+				changed++
+			}
+		case aorg.Quiz != nil && uploaded.Quiz != nil:
+			if aorg.Quiz.ID == 0 && uploaded.Quiz.ID != 0 {
+				aorg.Quiz.ID = uploaded.Quiz.ID
+				aorg.Quiz.AssignmentGroupID = uploaded.Quiz.AssignmentGroupID
+				edits = append(edits, idEdit{k, "quiz", "id", aorg.Quiz.ID})
+				if aorg.Quiz.AssignmentGroupID != 0 {
+					edits = append(edits, idEdit{k, "quiz", "assignment_group_id", aorg.Quiz.AssignmentGroupID})
+				}
+				changed++
+			}
+		case aorg.Discussion != nil && uploaded.Discussion != nil:
+			if aorg.Discussion.ID == 0 && uploaded.Discussion.ID != 0 {
+				aorg.Discussion.ID = uploaded.Discussion.ID
+				edits = append(edits, idEdit{k, "discussion", "id", aorg.Discussion.ID})
+				changed++
+			}
+		case aorg.Page != nil && uploaded.Page != nil:
+			// pages are identified by slug rather than ID
+			if aorg.Page.URL == "" && uploaded.Page.URL != "" {
+				aorg.Page.URL = uploaded.Page.URL
+				edits = append(edits, idEdit{k, "page", "url", aorg.Page.URL})
+				changed++
+			}
+		case aorg.Module != nil && uploaded.Module != nil:
+			if aorg.Module.ID == 0 && uploaded.Module.ID != 0 {
+				aorg.Module.ID = uploaded.Module.ID
+				edits = append(edits, idEdit{k, "module", "id", aorg.Module.ID})
+				changed++
+			}
+		default:
+			return 0, 0, fmt.Errorf("entry %d of %s does not match the uploaded entry", i, filename)
+		}
+	}
+	if changed == 0 || !write {
+		return changed + included, i, nil
+	}
+
+	if format == canvas.YAML {
+		return changed + included, i, editYAML(filename, edits)
+	}
+	return changed + included, i, writeTemplate(filename, format, original)
+}
+
+// idEdit sets a key in the object of one kind in the entry at an index of a
+// template file.
+type idEdit struct {
+	entry int
+	kind  string
+	key   string
+	value interface{}
+}
+
+// editYAML applies edits to a YAML template file without decoding it into
+// entries, which would lose its comments and layout.
+func editYAML(filename string, edits []idEdit) error {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var doc yaml3.Node
+	if err = yaml3.Unmarshal(contents, &doc); err != nil {
+		return fmt.Errorf("error parsing %s: %v", filename, err)
+	}
+	if doc.Kind != yaml3.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml3.SequenceNode {
+		return fmt.Errorf("%s is not a list of entries", filename)
+	}
+	list := doc.Content[0]
+	for _, edit := range edits {
+		if edit.entry >= len(list.Content) {
+			return fmt.Errorf("%s has fewer entries than expected", filename)
+		}
+		obj := yamlValue(list.Content[edit.entry], edit.kind)
+		if obj == nil {
+			return fmt.Errorf("entry %d of %s has no %s", edit.entry+1, filename, edit.kind)
+		}
+		if err = setYAMLKey(obj, edit.key, edit.value); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	if err = encoder.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	return replaceFile(filename, buf.Bytes())
+}
+
+// yamlValue finds the mapping under key in a YAML mapping. A value that is
+// an alias is replaced by a mapping that merges it, so that new keys do not
+// change the other entries that use the same anchor.
+func yamlValue(mapping *yaml3.Node, key string) *yaml3.Node {
+	if mapping.Kind != yaml3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		if value.Kind == yaml3.AliasNode {
+			merge := &yaml3.Node{Kind: yaml3.ScalarNode, Value: "<<"}
+			value = &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map", Content: []*yaml3.Node{merge, value}}
+			mapping.Content[i+1] = value
+		}
+		if value.Kind != yaml3.MappingNode {
+			return nil
+		}
+		return value
+	}
+	return nil
+}
+
+// setYAMLKey sets key to value in a YAML mapping, adding it if it is not there.
+func setYAMLKey(mapping *yaml3.Node, key string, value interface{}) error {
+	node := new(yaml3.Node)
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			node.Style |= mapping.Content[i+1].Style & yaml3.FlowStyle
+			mapping.Content[i+1] = node
+			return nil
+		}
+	}
+	node.Style |= mapping.Style & yaml3.FlowStyle
+	keyNode := &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key}
+	mapping.Content = append(mapping.Content, keyNode, node)
+	return nil
+}
+
+// writeTemplate replaces a template file with entries.
+func writeTemplate(filename string, format canvas.Format, entries []canvas.AssignmentOrGroup) error {
+	var buf bytes.Buffer
+	if err := canvas.Encode(&buf, entries, format); err != nil {
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	return replaceFile(filename, buf.Bytes())
+}
+
+// replaceFile replaces a file with contents, writing to a temporary file first
+// so a failure does not leave it half written.
+func replaceFile(filename string, contents []byte) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for %s: %v", filename, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
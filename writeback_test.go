@@ -0,0 +1,396 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/russross/canvasassignments/canvas"
+)
+
+// tempDir makes a directory with the given files in it
+func tempDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "writeback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const writebackJSON = `[
+	{"assignment_group": {"name": "Labs"}},
+	{"assignment": {"default": true, "points_possible": 10, "lock_after": "48h"}},
+	{"assignment": {"name": "Lab 1", "due_at": "2024-01-12 23:59:00"}},
+	{"assignment": {"id": 7, "name": "Lab 2"}},
+	{"assignment_group": {"id": 3, "name": "Exams"}},
+	{"assignment": {"name": "Midterm"}}
+]`
+
+const writebackYAML = `- assignment_group: {name: Labs}
+- assignment: {default: true, points_possible: 10, lock_after: 48h}
+- assignment: {name: Lab 1, due_at: "2024-01-12 23:59:00"}
+- assignment: {id: 7, name: Lab 2}
+- assignment_group: {id: 3, name: Exams}
+- assignment: {name: Midterm}
+`
+
+var writebackTests = []struct {
+	name     string
+	format   canvas.Format
+	contents string
+}{
+	{"course.json", canvas.JSON, writebackJSON},
+	{"course.yaml", canvas.YAML, writebackYAML},
+}
+
+// uploadedEntries stands in for the entries upload saw, with the IDs it assigned
+func uploadedEntries() []canvas.AssignmentOrGroup {
+	return []canvas.AssignmentOrGroup{
+		{Group: &canvas.AssignmentGroup{ID: 1, Name: "Labs"}},
+		{Assignment: &canvas.Assignment{ID: 5, Name: "Lab 1", AssignmentGroupID: 1, PointsPossible: 10}},
+		{Assignment: &canvas.Assignment{ID: 7, Name: "Lab 2", AssignmentGroupID: 1, PointsPossible: 10}},
+		{Group: &canvas.AssignmentGroup{ID: 3, Name: "Exams"}},
+		{Assignment: &canvas.Assignment{ID: 9, Name: "Midterm", AssignmentGroupID: 3}},
+	}
+}
+
+func TestWriteIDs(t *testing.T) {
+	for _, test := range writebackTests {
+		dir := tempDir(t, map[string]string{test.name: test.contents})
+		checkWriteIDs(t, filepath.Join(dir, test.name), test.format)
+		os.RemoveAll(dir)
+	}
+}
+
+func checkWriteIDs(t *testing.T, filename string, format canvas.Format) {
+	changed, err := writeIDs(filename, format, uploadedEntries())
+	if err != nil {
+		t.Fatalf("%s: %v", filename, err)
+	}
+	if changed != 3 {
+		t.Errorf("%s: changed %d entries, expected 3", filename, changed)
+	}
+
+	entries, err := read(filename, format)
+	if err != nil {
+		t.Fatalf("%s: %v", filename, err)
+	}
+	var got []string
+	for _, aorg := range entries {
+		switch {
+		case aorg.Group != nil:
+			got = append(got, "group "+aorg.Group.Name+" "+strconv.Itoa(aorg.Group.ID))
+		case aorg.Assignment.Default:
+			got = append(got, "default "+aorg.Assignment.LockAfter.String())
+		default:
+			asst := aorg.Assignment
+			got = append(got, "assignment "+asst.Name+" "+strconv.Itoa(asst.ID)+" in "+strconv.Itoa(asst.AssignmentGroupID))
+		}
+	}
+	want := []string{
+		"group Labs 1",
+		"default 48h0m0s",
+		"assignment Lab 1 5 in 1",
+		"assignment Lab 2 7 in 0",
+		"group Exams 3",
+		"assignment Midterm 9 in 3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s: got\n%s\nexpected\n%s", filename, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// defaults are not merged into the file
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(contents), "points_possible"); n != 1 {
+		t.Errorf("%s: points_possible appears %d times in\n%s", filename, n, contents)
+	}
+
+	// a second run has nothing to record
+	if changed, err := writeIDs(filename, format, uploadedEntries()); err != nil || changed != 0 {
+		t.Errorf("%s: second run changed %d entries, %v", filename, changed, err)
+	}
+}
+
+func TestWriteIDsMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []canvas.AssignmentOrGroup
+		want    string
+	}{
+		{"fewer", uploadedEntries()[:4], "has more entries than were uploaded"},
+		{"more", append(uploadedEntries(), canvas.AssignmentOrGroup{Assignment: &canvas.Assignment{ID: 11}}), "has fewer entries than were uploaded"},
+		{"different", append([]canvas.AssignmentOrGroup{uploadedEntries()[1]}, uploadedEntries()[1:]...), "does not match the uploaded entry"},
+	}
+	for _, test := range tests {
+		dir := tempDir(t, map[string]string{"course.json": writebackJSON})
+		filename := filepath.Join(dir, "course.json")
+		_, err := writeIDs(filename, canvas.JSON, test.entries)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, expected %q", test.name, err, test.want)
+		}
+
+		// the file is left alone
+		contents, _ := ioutil.ReadFile(filename)
+		if string(contents) != writebackJSON {
+			t.Errorf("%s: the file was changed to\n%s", test.name, contents)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWriteIDsSeries(t *testing.T) {
+	const contents = `[
+	{"series": {"name": "Lab {{n}}", "count": 3, "skip": [2], "ids": {"1": 11}}},
+	{"assignment": {"name": "Final"}}
+]`
+	dir := tempDir(t, map[string]string{"course.json": contents})
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "course.json")
+
+	uploaded := []canvas.AssignmentOrGroup{
+		{Assignment: &canvas.Assignment{ID: 11, Name: "Lab 1"}},
+		{Assignment: &canvas.Assignment{ID: 13, Name: "Lab 3"}},
+		{Assignment: &canvas.Assignment{ID: 20, Name: "Final"}},
+	}
+	changed, err := writeIDs(filename, canvas.JSON, uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("changed %d entries, expected 2", changed)
+	}
+
+	entries, err := read(filename, canvas.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Series == nil || entries[1].Assignment == nil {
+		t.Fatalf("got %d entries, expected a series and an assignment", len(entries))
+	}
+	if ids := entries[0].Series.IDs; len(ids) != 2 || ids[1] != 11 || ids[3] != 13 {
+		t.Errorf("got series IDs %v, expected map[1:11 3:13]", ids)
+	}
+	if id := entries[1].Assignment.ID; id != 20 {
+		t.Errorf("got assignment ID %d after the series, expected 20", id)
+	}
+
+	// a series that generated fewer assignments than were uploaded for it
+	if _, err := writeIDs(filename, canvas.JSON, uploaded[:1]); err == nil || !strings.Contains(err.Error(), `series "Lab {{n}}"`) {
+		t.Errorf("got %v, expected a series mismatch", err)
+	}
+}
+
+func TestWriteIDsInclude(t *testing.T) {
+	const course = `[
+	{"assignment_group": {"name": "Labs"}},
+	{"include": "labs.json"},
+	{"assignment": {"name": "Final"}}
+]`
+	const labs = `[
+	{"assignment": {"name": "Lab 1"}},
+	{"assignment": {"id": 6, "name": "Lab 2"}}
+]`
+	uploaded := func() []canvas.AssignmentOrGroup {
+		return []canvas.AssignmentOrGroup{
+			{Group: &canvas.AssignmentGroup{ID: 1, Name: "Labs"}},
+			{Assignment: &canvas.Assignment{ID: 5, Name: "Lab 1", AssignmentGroupID: 1}},
+			{Assignment: &canvas.Assignment{ID: 6, Name: "Lab 2", AssignmentGroupID: 1}},
+			{Assignment: &canvas.Assignment{ID: 8, Name: "Final", AssignmentGroupID: 1}},
+		}
+	}
+	dir := tempDir(t, map[string]string{"course.json": course, "labs.json": labs})
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "course.json")
+
+	// a mismatch in the including file leaves the included file alone
+	if _, err := writeIDs(filename, canvas.JSON, uploaded()[:3]); err == nil {
+		t.Errorf("expected an error with too few uploaded entries")
+	}
+	if contents, _ := ioutil.ReadFile(filepath.Join(dir, "labs.json")); string(contents) != labs {
+		t.Errorf("labs.json was changed to\n%s", contents)
+	}
+
+	changed, err := writeIDs(filename, canvas.JSON, uploaded())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("changed %d entries, expected 3", changed)
+	}
+
+	// IDs go to the file that has the entry, and the include is kept
+	entries, err := readFile(filename, canvas.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Group.ID != 1 || entries[1].Include != "labs.json" || entries[2].Assignment.ID != 8 {
+		t.Errorf("course.json was not updated as expected")
+	}
+	entries, err = readFile(filepath.Join(dir, "labs.json"), canvas.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Assignment.ID != 5 || entries[0].Assignment.AssignmentGroupID != 1 || entries[1].Assignment.ID != 6 {
+		t.Errorf("labs.json was not updated as expected")
+	}
+}
+
+func TestWriteIDsKeepsYAMLComments(t *testing.T) {
+	const contents = `# course template
+- assignment_group: {name: Labs}
+- assignment: &lab
+    # shared by both labs
+    name: Lab 1
+    points_possible: 10
+- assignment: *lab
+`
+	dir := tempDir(t, map[string]string{"course.yaml": contents})
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "course.yaml")
+
+	uploaded := []canvas.AssignmentOrGroup{
+		{Group: &canvas.AssignmentGroup{ID: 1, Name: "Labs"}},
+		{Assignment: &canvas.Assignment{ID: 5, Name: "Lab 1", AssignmentGroupID: 1}},
+		{Assignment: &canvas.Assignment{ID: 6, Name: "Lab 1", AssignmentGroupID: 1}},
+	}
+	changed, err := writeIDs(filename, canvas.YAML, uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("changed %d entries, expected 3", changed)
+	}
+
+	written, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# course template", "# shared by both labs"} {
+		if !strings.Contains(string(written), comment) {
+			t.Errorf("the comment %q was lost:\n%s", comment, written)
+		}
+	}
+
+	// each entry that shares the anchor gets its own ID
+	entries, err := readFile(filename, canvas.YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Group.ID != 1 || entries[1].Assignment.ID != 5 || entries[2].Assignment.ID != 6 {
+		t.Errorf("got IDs that do not match the uploaded entries in\n%s", written)
+	}
+	if entries[2].Assignment.PointsPossible != 10 {
+		t.Errorf("the second lab lost the shared fields in\n%s", written)
+	}
+}
+
+func TestWriteFileIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		format canvas.Format
+		files  map[string]string
+	}{
+		{"course.json", canvas.JSON, map[string]string{
+			"course.json": `[
+	{"assignment_group": {"name": "Labs"}},
+	{"series": {"name": "Lab {{n}}", "count": 3, "skip": [2]}},
+	{"include": "exams.yaml"}
+]`,
+			"exams.yaml": `# exams go in their own group
+- assignment_group: {id: 3, name: Exams}
+- assignment: {name: Midterm}
+`,
+		}},
+		{"course.yaml", canvas.YAML, map[string]string{
+			"course.yaml": `# labs first
+- assignment_group: {name: Labs}
+- series: {name: "Lab {{n}}", count: 3, skip: [2]}
+- include: exams.json
+`,
+			"exams.json": `[
+	{"assignment_group": {"id": 3, "name": "Exams"}},
+	{"assignment": {"name": "Midterm"}}
+]`,
+		}},
+	}
+	uploaded := []canvas.AssignmentOrGroup{
+		{Group: &canvas.AssignmentGroup{ID: 1, Name: "Labs"}},
+		{Assignment: &canvas.Assignment{ID: 11, Name: "Lab 1", AssignmentGroupID: 1}},
+		{Assignment: &canvas.Assignment{ID: 13, Name: "Lab 3", AssignmentGroupID: 1}},
+		{Group: &canvas.AssignmentGroup{ID: 3, Name: "Exams"}},
+		{Assignment: &canvas.Assignment{ID: 20, Name: "Midterm", AssignmentGroupID: 3}},
+	}
+
+	for _, test := range tests {
+		dir := tempDir(t, test.files)
+		filename := filepath.Join(dir, test.name)
+
+		changed, err := writeIDs(filename, test.format, uploaded)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			os.RemoveAll(dir)
+			continue
+		}
+		if changed != 4 {
+			t.Errorf("%s: changed %d entries, expected 4", test.name, changed)
+		}
+
+		// walk the file and the file it includes
+		var got []string
+		var walk func(string)
+		walk = func(name string) {
+			entries, err := readFile(name, canvas.FormatFor(name))
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			for _, aorg := range entries {
+				switch {
+				case aorg.Include != "":
+					walk(includePath(name, aorg.Include))
+				case aorg.Group != nil:
+					got = append(got, "group "+aorg.Group.Name+" "+strconv.Itoa(aorg.Group.ID))
+				case aorg.Series != nil:
+					ids := aorg.Series.IDs
+					got = append(got, "series "+strconv.Itoa(ids[1])+" "+strconv.Itoa(ids[2])+" "+strconv.Itoa(ids[3]))
+				case aorg.Assignment != nil:
+					asst := aorg.Assignment
+					got = append(got, "assignment "+asst.Name+" "+strconv.Itoa(asst.ID)+" in "+strconv.Itoa(asst.AssignmentGroupID))
+				}
+			}
+		}
+		walk(filename)
+		want := []string{"group Labs 1", "series 11 0 13", "group Exams 3", "assignment Midterm 20 in 3"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got\n%s\nexpected\n%s", test.name, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+
+		// YAML files keep their comments
+		for name := range test.files {
+			if canvas.FormatFor(name) != canvas.YAML {
+				continue
+			}
+			contents, _ := ioutil.ReadFile(filepath.Join(dir, name))
+			if !strings.HasPrefix(string(contents), "#") {
+				t.Errorf("%s: %s lost its comment:\n%s", test.name, name, contents)
+			}
+		}
+
+		// a second run has nothing to record
+		if changed, err := writeIDs(filename, test.format, uploaded); err != nil || changed != 0 {
+			t.Errorf("%s: second run changed %d entries, %v", test.name, changed, err)
+		}
+		os.RemoveAll(dir)
+	}
+}